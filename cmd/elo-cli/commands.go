@@ -0,0 +1,828 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/melee-elo-ranking/internal/config"
+	"github.com/melee-elo-ranking/internal/elo"
+	"github.com/melee-elo-ranking/internal/generator"
+	"github.com/melee-elo-ranking/internal/impact"
+	"github.com/melee-elo-ranking/internal/melee"
+	"github.com/melee-elo-ranking/internal/parser"
+	"github.com/melee-elo-ranking/internal/server"
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+// app is the state shared by every subcommand.
+type app struct {
+	cfg   *config.Config
+	store *storage.Storage
+}
+
+// command is an elo-cli subcommand.
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(a *app, args []string) error
+}
+
+var commands = []command{
+	{"run", "[flags]", "Ingest pending files, update ratings and render the site", runRun},
+	{"ingest", "[flags]", "Ingest pending files and update ratings", runIngest},
+	{"rebuild", "", "Replay every stored match from scratch", runRebuild},
+	{"render", "", "Render every ranking profile from the database", runRender},
+	{"rankings", "[-limit n] [-profile name] [-as-of YYYY-MM-DD]", "Print the current or past rankings", runRankings},
+	{"player", "<name> | set [flags] <name>", "Print a player's rating and match history, or edit their profile", runPlayer},
+	{"h2h", "<player> <opponent>", "Print the head-to-head record of two players", runHeadToHead},
+	{"tournament", "set-date <id> <YYYY-MM-DD>", "Set a tournament's date, release it from the needs-date queue and update ratings", runTournament},
+	{"serve", "[-addr host:port]", "Serve live rankings as HTML and JSON over HTTP", runServe},
+}
+
+func findCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: elo-cli [global flags] <command> [args]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-38s %s\n", strings.TrimSpace(c.name+" "+c.args), c.summary)
+	}
+	fmt.Fprintf(out, "\nWithout a command, run is used.\n\nGlobal flags:\n")
+	flag.PrintDefaults()
+}
+
+// ingestFlags are shared by the run and ingest commands.
+type ingestFlags struct {
+	dates          *string
+	nonInteractive *bool
+}
+
+func addIngestFlags(fs *flag.FlagSet) ingestFlags {
+	return ingestFlags{
+		dates:          fs.String("dates", "", "Tournament dates in format: 170676=2024-08-31,172453=2024-10-17"),
+		nonInteractive: fs.Bool("non-interactive", false, "Never prompt for tournament dates; park tournaments with unknown dates in the needs-date queue"),
+	}
+}
+
+func (a *app) newProcessor(f ingestFlags) *Processor {
+	if f.nonInteractive != nil && *f.nonInteractive {
+		a.cfg.Processing.NonInteractive = true
+	}
+	var datesMap map[int]string
+	if f.dates != nil {
+		datesMap = parseTournamentDates(*f.dates)
+	}
+	return NewProcessor(a.store, newCalculator(a.cfg.ELO), parser.New(), melee.NewClient(), datesMap, a.cfg)
+}
+
+func runRun(a *app, args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	f := addIngestFlags(fs)
+	dryRun := fs.Bool("dry-run", false, "Preview rating changes from pending files without moving them or writing to the database")
+	fs.Parse(args)
+
+	processor := a.newProcessor(f)
+	if *dryRun {
+		return runDryRun(a.store, processor.calculator, processor.parser, processor.meleeClient, processor.tournamentDates, a.cfg)
+	}
+
+	if err := processor.Process(); err != nil {
+		return fmt.Errorf("failed to process matches: %w", err)
+	}
+	return render(a.store, a.cfg)
+}
+
+func runIngest(a *app, args []string) error {
+	fs := flag.NewFlagSet("ingest", flag.ExitOnError)
+	f := addIngestFlags(fs)
+	fs.Parse(args)
+
+	if err := a.newProcessor(f).Process(); err != nil {
+		return fmt.Errorf("failed to process matches: %w", err)
+	}
+	return nil
+}
+
+func runRebuild(a *app, args []string) error {
+	fs := flag.NewFlagSet("rebuild", flag.ExitOnError)
+	fs.Parse(args)
+
+	return a.newProcessor(ingestFlags{}).Rebuild()
+}
+
+func runRender(a *app, args []string) error {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	fs.Parse(args)
+
+	return render(a.store, a.cfg)
+}
+
+// runDryRun processes pending files against an in-memory copy of the
+// database and prints how every affected player's rating and rank would move.
+func runDryRun(store *storage.Storage, calculator *elo.Calculator, matchParser *parser.Parser, meleeClient *melee.Client, datesMap map[int]string, cfg *config.Config) error {
+	memStore, err := store.CopyToMemory()
+	if err != nil {
+		return err
+	}
+	defer memStore.Close()
+
+	before, err := impact.Take(memStore, cfg.Output.MinMatches)
+	if err != nil {
+		return err
+	}
+
+	processor := NewProcessor(memStore, calculator, matchParser, meleeClient, datesMap, cfg)
+	processor.SetDryRun(true)
+	if err := processor.Process(); err != nil {
+		return err
+	}
+
+	after, err := impact.Take(memStore, cfg.Output.MinMatches)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Dry run: no files were moved and the database was not modified")
+	impact.Compare(before, after).Print(os.Stdout)
+	return nil
+}
+
+// render writes the index of every ranking profile, the player pages and
+// the matchup matrix. Player pages and the matrix follow the main profile.
+func render(store *storage.Storage, cfg *config.Config) error {
+	profiles := cfg.RankingProfiles()
+	links := make([]generator.ProfileLink, len(profiles))
+	for i, p := range profiles {
+		links[i] = generator.ProfileLink{Title: p.Title, Output: p.Output}
+	}
+
+	players, err := store.GetAllPlayers()
+	if err != nil {
+		return fmt.Errorf("failed to get players: %w", err)
+	}
+	slugs := generator.PlayerSlugs(players)
+
+	// Regions and clubs get their own leaderboards, linked from the main index
+	regions, err := store.GetGroups(storage.GroupRegion)
+	if err != nil {
+		return fmt.Errorf("failed to get regions: %w", err)
+	}
+	clubs, err := store.GetGroups(storage.GroupTeam)
+	if err != nil {
+		return fmt.Errorf("failed to get clubs: %w", err)
+	}
+
+	var rankings []storage.Ranking
+	var gen *generator.Generator
+	for i, profile := range profiles {
+		profileRanks, err := profileRankings(store, profile)
+		if err != nil {
+			return fmt.Errorf("failed to rank profile %s: %w", profile.Name, err)
+		}
+
+		// Generate HTML
+		profileGen := generator.New(profile.Title, profile.Description)
+		profileGen.SetProfiles(links)
+		profileGen.SetSlugs(slugs)
+		if i == 0 {
+			if err := setRankingSnapshots(store, profileGen); err != nil {
+				log.Printf("Warning: Failed to get ranking snapshots: %v", err)
+			}
+			profileGen.SetLeaderboards(regions, clubs)
+		}
+		if err := os.MkdirAll(filepath.Dir(profile.Output), 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		if err := profileGen.Generate(profileRanks, profile.Output); err != nil {
+			return fmt.Errorf("failed to generate HTML for profile %s: %w", profile.Name, err)
+		}
+		if i > 0 {
+			log.Printf("Generated %s rankings at %s", profile.Name, profile.Output)
+			continue
+		}
+		rankings, gen = profileRanks, profileGen
+	}
+
+	// Only ranked players get a page to link to
+	rankedNames := make([]string, len(rankings))
+	for i, r := range rankings {
+		rankedNames[i] = r.DisplayName
+	}
+	gen.SetPlayerPages(rankedNames)
+	gen.SetMatchupMinSets(cfg.Output.MatchupMinSets)
+
+	// Player charts can be compared with the field and other ranked players,
+	// and player pages list how they performed at each tournament
+	dated, err := store.GetDatedMatches()
+	if err != nil {
+		log.Printf("Warning: Failed to get rating history: %v", err)
+	} else {
+		gen.SetRatingHistory(dated, rankedNames)
+		gen.SetPerformances(storage.Performances(dated))
+	}
+
+	playerProfiles, err := store.GetPlayerProfiles()
+	if err != nil {
+		log.Printf("Warning: Failed to get player profiles: %v", err)
+	} else {
+		gen.SetPlayerProfiles(playerProfiles)
+	}
+
+	// Generate player detail pages
+	playersDir := "docs/players"
+	if err := os.MkdirAll(playersDir, 0755); err != nil {
+		return fmt.Errorf("failed to create players directory: %w", err)
+	}
+
+	// The static JSON API mirrors the HTML pages
+	apiDir := "docs/api"
+	if err := os.MkdirAll(apiDir+"/players", 0755); err != nil {
+		return fmt.Errorf("failed to create api directory: %w", err)
+	}
+	if err := gen.GenerateRankingsJSON(rankings, apiDir+"/rankings.json"); err != nil {
+		log.Printf("Warning: Failed to generate rankings JSON: %v", err)
+	}
+
+	// Files of players who opted out or dropped out of the ranking are
+	// removed afterwards, so nothing stays published under an old name
+	writtenPages := make(map[string]bool)
+	writtenJSON := make(map[string]bool)
+	for _, r := range rankings {
+		matches, err := store.GetPlayerMatchHistory(r.DisplayName)
+		if err != nil {
+			log.Printf("Warning: Failed to get match history for %s: %v", r.DisplayName, err)
+			continue
+		}
+
+		slug := slugs.Slug(r.DisplayName)
+		playerPath := playersDir + "/" + slug + ".html"
+		if err := gen.GeneratePlayerPage(r.DisplayName, matches, r, playerPath); err != nil {
+			log.Printf("Warning: Failed to generate player page for %s: %v", r.DisplayName, err)
+			continue
+		}
+		writtenPages[filepath.Base(playerPath)] = true
+		if oldPath, ok := legacyPlayerPath(playersDir, r.DisplayName, slugs); ok {
+			if err := gen.GenerateRedirect(slug+".html", oldPath); err != nil {
+				log.Printf("Warning: Failed to write redirect for %s: %v", r.DisplayName, err)
+			} else {
+				writtenPages[filepath.Base(oldPath)] = true
+			}
+		}
+		playerJSONPath := apiDir + "/players/" + slug + ".json"
+		if err := gen.GeneratePlayerJSON(r.DisplayName, matches, r, playerJSONPath); err != nil {
+			log.Printf("Warning: Failed to generate player JSON for %s: %v", r.DisplayName, err)
+		} else {
+			writtenJSON[filepath.Base(playerJSONPath)] = true
+		}
+	}
+	if err := pruneDir(playersDir, ".html", writtenPages); err != nil {
+		log.Printf("Warning: Failed to remove old player pages: %v", err)
+	}
+	if err := pruneDir(apiDir+"/players", ".json", writtenJSON); err != nil {
+		log.Printf("Warning: Failed to remove old player JSON: %v", err)
+	}
+
+	log.Println("Successfully generated rankings at", cfg.Paths.Output)
+	log.Println("Generated player pages in", playersDir)
+
+	if err := renderLeaderboards(store, gen, cfg, regions, clubs); err != nil {
+		log.Printf("Warning: Failed to generate leaderboards: %v", err)
+	}
+	if err := renderTournaments(store, gen, apiDir); err != nil {
+		log.Printf("Warning: Failed to generate tournament pages: %v", err)
+	}
+	if err := renderHeadToHeads(store, gen); err != nil {
+		log.Printf("Warning: Failed to generate head-to-head pages: %v", err)
+	}
+
+	recordsPath := "docs/records.html"
+	if err := gen.GenerateRecords(dated, recordsPath); err != nil {
+		log.Printf("Warning: Failed to generate records: %v", err)
+	} else {
+		log.Println("Generated records at", recordsPath)
+	}
+
+	// Generate matchup matrix
+	matchups, err := store.GetMatchups()
+	if err != nil {
+		log.Printf("Warning: Failed to get matchups: %v", err)
+		return nil
+	}
+
+	matchupPath := "docs/matchups.html"
+	if err := gen.GenerateMatchupMatrix(matchups, rankedNames, matchupPath); err != nil {
+		log.Printf("Warning: Failed to generate matchup matrix: %v", err)
+	} else {
+		log.Println("Generated matchup matrix at", matchupPath)
+	}
+	if err := gen.GenerateMatchupsJSON(matchups, rankedNames, apiDir+"/matchups.json"); err != nil {
+		log.Printf("Warning: Failed to generate matchups JSON: %v", err)
+	} else {
+		log.Println("Generated JSON API in", apiDir)
+	}
+	return nil
+}
+
+// setRankingSnapshots gives the main ranking its movement since the
+// previous snapshot and every player page its rank history.
+func setRankingSnapshots(store *storage.Storage, gen *generator.Generator) error {
+	last, err := store.GetLastRatedTournament()
+	if err != nil || last == nil {
+		return err
+	}
+	previous, err := store.GetRankingSnapshotBefore(last.Date)
+	if err != nil {
+		return err
+	}
+	history, err := store.GetRankingSnapshots()
+	if err != nil {
+		return err
+	}
+	gen.SetPreviousRanking(previous)
+	gen.SetRankHistory(history)
+	return nil
+}
+
+// renderTournaments writes a results page for every tournament, the list of
+// tournaments and api/tournaments.json.
+func renderTournaments(store *storage.Storage, gen *generator.Generator, apiDir string) error {
+	tournaments, err := store.GetAllTournaments()
+	if err != nil {
+		return err
+	}
+
+	tournamentsDir := "docs/tournaments"
+	if err := os.MkdirAll(tournamentsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create tournaments directory: %w", err)
+	}
+	for _, t := range tournaments {
+		matches, err := store.GetTournamentResults(t.MeleeID)
+		if err != nil {
+			log.Printf("Warning: Failed to get results of tournament %d: %v", t.MeleeID, err)
+			continue
+		}
+		pagePath := fmt.Sprintf("%s/%d.html", tournamentsDir, t.MeleeID)
+		if err := gen.GenerateTournamentPage(t, matches, pagePath); err != nil {
+			log.Printf("Warning: Failed to generate page for tournament %d: %v", t.MeleeID, err)
+		}
+	}
+	if err := gen.GenerateTournamentList(tournaments, tournamentsDir+"/index.html"); err != nil {
+		return err
+	}
+	log.Println("Generated tournament pages in", tournamentsDir)
+
+	return gen.GenerateTournamentsJSON(tournaments, apiDir+"/tournaments.json")
+}
+
+// renderLeaderboards writes the leaderboard of every region and club, and
+// the club table if there are clubs. Leaderboards of regions and clubs that
+// no published profile names any more are removed.
+func renderLeaderboards(store *storage.Storage, gen *generator.Generator, cfg *config.Config, regions, clubs []string) error {
+	groups := []struct {
+		group storage.PlayerGroup
+		dir   string
+		names []string
+	}{
+		{storage.GroupRegion, "docs/regions", regions},
+		{storage.GroupTeam, "docs/clubs", clubs},
+	}
+	// The files written to each directory, by name
+	written := map[string]map[string]bool{"docs/regions": {}, "docs/clubs": {}}
+	for _, g := range groups {
+		for _, name := range g.names {
+			rankings, err := store.GetGroupRankings(g.group, name, cfg.Output.MinMatches)
+			if err != nil {
+				log.Printf("Warning: Failed to rank %s: %v", name, err)
+				continue
+			}
+			pagePath := filepath.Join("docs", gen.LeaderboardPath(g.group, name))
+			if err := os.MkdirAll(filepath.Dir(pagePath), 0755); err != nil {
+				return fmt.Errorf("failed to create leaderboard directory: %w", err)
+			}
+			if err := gen.GenerateLeaderboard(g.group, name, rankings, pagePath); err != nil {
+				log.Printf("Warning: Failed to generate leaderboard for %s: %v", name, err)
+				continue
+			}
+			written[g.dir][filepath.Base(pagePath)] = true
+		}
+	}
+	if len(regions) > 0 || len(clubs) > 0 {
+		log.Printf("Generated %d region and %d club leaderboards", len(regions), len(clubs))
+	}
+
+	if len(clubs) > 0 {
+		table, err := store.GetClubRankings(cfg.Output.ClubTopMembers, cfg.Output.MinMatches)
+		if err != nil {
+			return err
+		}
+		tablePath := "docs/clubs/index.html"
+		if err := gen.GenerateClubTable(table, cfg.Output.ClubTopMembers, tablePath); err != nil {
+			return err
+		}
+		written["docs/clubs"][filepath.Base(tablePath)] = true
+	}
+
+	for dir, files := range written {
+		if err := pruneDir(dir, ".html", files); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderHeadToHeads writes a page for every pair of players that has played.
+func renderHeadToHeads(store *storage.Storage, gen *generator.Generator) error {
+	matches, err := store.GetDatedMatches()
+	if err != nil {
+		return err
+	}
+
+	h2hDir := "docs/h2h"
+	if err := os.MkdirAll(h2hDir, 0755); err != nil {
+		return fmt.Errorf("failed to create h2h directory: %w", err)
+	}
+	written := make(map[string]bool)
+	for _, h := range gen.HeadToHeads(matches) {
+		pagePath := h2hDir + "/" + gen.HeadToHeadSlug(h.Player1, h.Player2) + ".html"
+		if err := gen.GenerateHeadToHeadPage(h, pagePath); err != nil {
+			log.Printf("Warning: Failed to generate head-to-head page for %s vs %s: %v", h.Player1, h.Player2, err)
+			continue
+		}
+		written[filepath.Base(pagePath)] = true
+	}
+	log.Println("Generated head-to-head pages in", h2hDir)
+	return pruneDir(h2hDir, ".html", written)
+}
+
+// pruneDir removes the files with extension ext in dir that are not in
+// written, the files this render wrote there.
+func pruneDir(dir, ext string, written map[string]bool) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ext || written[name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// legacyPlayerPath returns where a player's page was written before pages
+// were named by slug, if a redirect can be left there. Names that were never
+// valid file names, or whose old path is another player's page on a
+// case-insensitive file system, are skipped.
+func legacyPlayerPath(playersDir, name string, slugs *generator.Slugs) (string, bool) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", false
+	}
+	slug := slugs.Slug(name)
+	if name == slug {
+		return "", false
+	}
+	if other, taken := slugs.Name(strings.ToLower(name)); taken && other != name {
+		return "", false
+	}
+	oldPath := playersDir + "/" + name + ".html"
+	// "Alice.html" is the same file as "alice.html" on macOS and Windows
+	oldInfo, oldErr := os.Stat(oldPath)
+	newInfo, newErr := os.Stat(playersDir + "/" + slug + ".html")
+	if oldErr == nil && newErr == nil && os.SameFile(oldInfo, newInfo) {
+		return "", false
+	}
+	return oldPath, true
+}
+
+func runRankings(a *app, args []string) error {
+	fs := flag.NewFlagSet("rankings", flag.ExitOnError)
+	limit := fs.Int("limit", 0, "Only print the top n players (0 prints everyone)")
+	profileName := fs.String("profile", config.MainProfile, "Ranking profile to print")
+	asOf := fs.String("as-of", "", "Print the rankings as they stood after the tournaments of this date (YYYY-MM-DD)")
+	fs.Parse(args)
+
+	profile, ok := findProfile(a.cfg, *profileName)
+	if !ok {
+		return fmt.Errorf("unknown profile %q", *profileName)
+	}
+	var rankings []storage.Ranking
+	var err error
+	if *asOf != "" {
+		rankings, err = rankingsAsOf(a.store, profile, *asOf)
+	} else {
+		rankings, err = profileRankings(a.store, profile)
+	}
+	if err != nil {
+		return err
+	}
+	if *limit > 0 && *limit < len(rankings) {
+		rankings = rankings[:*limit]
+	}
+	printRankings(os.Stdout, rankings)
+	return nil
+}
+
+// rankingsAsOf ranks the players of a profile as of the end of a date. The
+// main profile reads the stored rating history; other profiles are replayed
+// up to the date.
+func rankingsAsOf(store *storage.Storage, profile config.ProfileConfig, asOf string) ([]storage.Ranking, error) {
+	date, err := time.Parse("2006-01-02", asOf)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", asOf)
+	}
+	if profile.Name == config.MainProfile {
+		return store.GetRankingsAsOf(date, profile.MinMatches)
+	}
+	if until, err := time.Parse("2006-01-02", profile.Filter.Until); err != nil || until.After(date) {
+		profile.Filter.Until = asOf
+	}
+	return profileRankings(store, profile)
+}
+
+func findProfile(cfg *config.Config, name string) (config.ProfileConfig, bool) {
+	for _, p := range cfg.RankingProfiles() {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return config.ProfileConfig{}, false
+}
+
+func printRankings(w io.Writer, rankings []storage.Ranking) {
+	if len(rankings) == 0 {
+		fmt.Fprintln(w, "No ranked players")
+		return
+	}
+
+	fmt.Fprintf(w, "%4s  %-24s %5s %7s %6s\n", "Rank", "Player", "ELO", "W-L", "Win%")
+	for _, r := range rankings {
+		fmt.Fprintf(w, "%4d  %-24s %5d %7s %5.1f%%\n",
+			r.Rank, r.DisplayName, r.CurrentELO, fmt.Sprintf("%d-%d", r.Wins, r.Losses), r.WinRate)
+	}
+}
+
+// findPlayer looks a player up by display name, ignoring case if there is no
+// exact match.
+func findPlayer(store *storage.Storage, name string) (*storage.Player, error) {
+	players, err := store.GetAllPlayers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get players: %w", err)
+	}
+
+	var folded *storage.Player
+	for i := range players {
+		if players[i].DisplayName == name {
+			return &players[i], nil
+		}
+		if folded == nil && strings.EqualFold(players[i].DisplayName, name) {
+			folded = &players[i]
+		}
+	}
+	if folded == nil {
+		return nil, fmt.Errorf("no player named %q", name)
+	}
+	return folded, nil
+}
+
+func runPlayer(a *app, args []string) error {
+	if len(args) > 0 && args[0] == "set" {
+		return runPlayerSet(a, args[1:])
+	}
+	fs := flag.NewFlagSet("player", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: elo-cli player <name>")
+	}
+
+	player, err := findPlayer(a.store, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	history, err := a.store.GetPlayerMatchHistory(player.DisplayName)
+	if err != nil {
+		return fmt.Errorf("failed to get match history: %w", err)
+	}
+
+	printPlayer(os.Stdout, *player, history)
+	return nil
+}
+
+// runPlayerSet handles "player set [flags] <name>": it changes the given
+// fields of a player's profile and prints the result. Players are looked up
+// by their real name, so opted-out players can still be edited.
+func runPlayerSet(a *app, args []string) error {
+	fs := flag.NewFlagSet("player set", flag.ExitOnError)
+	region := fs.String("region", "", "Region the player represents")
+	team := fs.String("team", "", "Team or club the player belongs to")
+	character := fs.String("main", "", "Character the player mains")
+	links := fs.String("links", "", "Comma-separated social links")
+	optOut := fs.Bool("opt-out", false, "Anonymize the player on every published page and in the JSON (-opt-out=false reverses it)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: elo-cli player set [-region r] [-team t] [-main c] [-links urls] [-opt-out] <name>")
+	}
+
+	player, err := a.store.FindPlayer(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to find player: %w", err)
+	}
+	if player == nil {
+		return fmt.Errorf("no player named %q", fs.Arg(0))
+	}
+	profile, err := a.store.GetPlayerProfile(player.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get profile: %w", err)
+	}
+	if profile == nil {
+		profile = &storage.PlayerProfile{PlayerID: player.ID, DisplayName: player.DisplayName}
+	}
+
+	// Only the flags that were given change the profile
+	changed := false
+	fs.Visit(func(f *flag.Flag) {
+		changed = true
+		switch f.Name {
+		case "region":
+			profile.Region = strings.TrimSpace(*region)
+		case "team":
+			profile.Team = strings.TrimSpace(*team)
+		case "main":
+			profile.Main = strings.TrimSpace(*character)
+		case "links":
+			profile.Links = nil
+			for _, link := range strings.Split(*links, ",") {
+				if link = strings.TrimSpace(link); link != "" {
+					profile.Links = append(profile.Links, link)
+				}
+			}
+		case "opt-out":
+			profile.OptOut = *optOut
+		}
+	})
+	if changed {
+		if err := a.store.SavePlayerProfile(*profile); err != nil {
+			return fmt.Errorf("failed to save profile: %w", err)
+		}
+	}
+
+	printProfile(os.Stdout, *profile)
+	if changed {
+		fmt.Println("\nRender again to update the published pages")
+	}
+	return nil
+}
+
+func printProfile(w io.Writer, profile storage.PlayerProfile) {
+	orNone := func(value string) string {
+		if value == "" {
+			return "-"
+		}
+		return value
+	}
+	fmt.Fprintf(w, "%s\n", profile.DisplayName)
+	fmt.Fprintf(w, "  %-8s %s\n", "Region", orNone(profile.Region))
+	fmt.Fprintf(w, "  %-8s %s\n", "Team", orNone(profile.Team))
+	fmt.Fprintf(w, "  %-8s %s\n", "Main", orNone(profile.Main))
+	fmt.Fprintf(w, "  %-8s %s\n", "Links", orNone(strings.Join(profile.Links, ", ")))
+	if profile.OptOut {
+		fmt.Fprintf(w, "  %-8s %s\n", "Opt-out", "yes, anonymized on published pages")
+	} else {
+		fmt.Fprintf(w, "  %-8s %s\n", "Opt-out", "no")
+	}
+}
+
+func printPlayer(w io.Writer, player storage.Player, history []storage.PlayerMatch) {
+	fmt.Fprintf(w, "%s: %d ELO, %d-%d in %d sets\n\n",
+		player.DisplayName, player.CurrentELO, player.Wins, player.Losses, player.MatchesPlayed)
+	printHistory(w, history)
+}
+
+func printHistory(w io.Writer, history []storage.PlayerMatch) {
+	if len(history) == 0 {
+		fmt.Fprintln(w, "No matches")
+		return
+	}
+
+	fmt.Fprintf(w, "%-10s %5s  %-24s %5s %-4s  %s\n", "Date", "Round", "Opponent", "Score", "", "ELO")
+	for _, m := range history {
+		fmt.Fprintf(w, "%-10s %5d  %-24s %5s %-4s  %d -> %d\n",
+			m.DatePlayed.Format("2006-01-02"), m.Round, m.OpponentName,
+			fmt.Sprintf("%d-%d", m.PlayerWins, m.OpponentWins), m.Result,
+			m.PlayerELOBefore, m.PlayerELOAfter)
+	}
+}
+
+func runHeadToHead(a *app, args []string) error {
+	fs := flag.NewFlagSet("h2h", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: elo-cli h2h <player> <opponent>")
+	}
+
+	player, err := findPlayer(a.store, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	opponent, err := findPlayer(a.store, fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	history, err := a.store.GetPlayerMatchHistory(player.DisplayName)
+	if err != nil {
+		return fmt.Errorf("failed to get match history: %w", err)
+	}
+
+	printHeadToHead(os.Stdout, player.DisplayName, opponent.DisplayName, history)
+	return nil
+}
+
+// printHeadToHead prints the sets in history played against opponent.
+func printHeadToHead(w io.Writer, player, opponent string, history []storage.PlayerMatch) {
+	var sets []storage.PlayerMatch
+	var setWins, setLosses, gameWins, gameLosses int
+	for _, m := range history {
+		if m.OpponentName != opponent {
+			continue
+		}
+		sets = append(sets, m)
+		gameWins += m.PlayerWins
+		gameLosses += m.OpponentWins
+		switch m.Result {
+		case "Win":
+			setWins++
+		case "Loss":
+			setLosses++
+		}
+	}
+
+	fmt.Fprintf(w, "%s vs %s: %d-%d in sets, %d-%d in games\n\n", player, opponent, setWins, setLosses, gameWins, gameLosses)
+	printHistory(w, sets)
+}
+
+// runTournament handles "tournament set-date <id> <YYYY-MM-DD>". It
+// releases the tournament from the needs-date queue, then ingests pending
+// files and updates ratings like ingest does.
+func runTournament(a *app, args []string) error {
+	if len(args) != 3 || args[0] != "set-date" {
+		return fmt.Errorf("usage: elo-cli tournament set-date <id> <YYYY-MM-DD>")
+	}
+
+	id, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid tournament ID %q", args[1])
+	}
+	date, err := time.Parse("2006-01-02", args[2])
+	if err != nil {
+		return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", args[2])
+	}
+
+	processor := a.newProcessor(ingestFlags{})
+	released, err := processor.SetTournamentDate(id, date)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Set date of tournament %d to %s, released %d file(s) from the needs-date queue\n", id, args[2], released)
+
+	if err := processor.Process(); err != nil {
+		return fmt.Errorf("failed to process matches: %w", err)
+	}
+	return nil
+}
+
+func runServe(a *app, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "Address to listen on")
+	fs.Parse(args)
+
+	// Uploads must never wait on a prompt
+	a.cfg.Processing.NonInteractive = true
+	var ingester server.Ingester
+	if a.cfg.Server.UploadToken != "" {
+		ingester = a.newProcessor(ingestFlags{})
+	} else {
+		log.Println("No server.upload_token configured, uploads are disabled")
+	}
+
+	log.Printf("Serving rankings from %s on http://%s", a.cfg.Paths.Database, *addr)
+	return http.ListenAndServe(*addr, server.New(a.store, a.cfg, ingester))
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/melee-elo-ranking/internal/generator"
+)
+
+func TestFindCommand(t *testing.T) {
+	for _, name := range []string{"run", "ingest", "rebuild", "render", "rankings", "player", "h2h", "tournament", "serve"} {
+		if _, ok := findCommand(name); !ok {
+			t.Errorf("expected command %q", name)
+		}
+	}
+	if _, ok := findCommand("unknown"); ok {
+		t.Error("expected unknown command not to be found")
+	}
+}
+
+func TestFindPlayerAndHeadToHead(t *testing.T) {
+	p := newTestProcessor(t, map[int]string{1: "2024-08-31"})
+	writePending(t, p, "Matches-tournament-1.json", v2Export(
+		testMatch{Round: 1, Player1: "Alice", Player1Wins: 2, Player2: "Bob", Player2Wins: 1},
+		testMatch{Round: 2, Player1: "Bob", Player1Wins: 2, Player2: "Alice", Player2Wins: 0},
+		testMatch{Round: 3, Player1: "Alice", Player1Wins: 2, Player2: "Carol", Player2Wins: 0},
+	))
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	alice, err := findPlayer(p.store, "alice")
+	if err != nil {
+		t.Fatalf("findPlayer failed: %v", err)
+	}
+	if alice.DisplayName != "Alice" {
+		t.Errorf("expected case-insensitive match on Alice, got %s", alice.DisplayName)
+	}
+	if _, err := findPlayer(p.store, "Dave"); err == nil {
+		t.Error("expected error for unknown player")
+	}
+
+	history, err := p.store.GetPlayerMatchHistory("Alice")
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+
+	var out bytes.Buffer
+	printHeadToHead(&out, "Alice", "Bob", history)
+	if !strings.HasPrefix(out.String(), "Alice vs Bob: 1-1 in sets, 2-3 in games") {
+		t.Errorf("unexpected summary:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), "Carol") {
+		t.Errorf("expected only sets against Bob:\n%s", out.String())
+	}
+}
+
+func TestLegacyPlayerPath(t *testing.T) {
+	dir := t.TempDir()
+	slugs := generator.NewSlugs([]string{"Szitsu Szitsu", "alice", "Alice", "AC/DC"})
+
+	if path, ok := legacyPlayerPath(dir, "Szitsu Szitsu", slugs); !ok || path != dir+"/Szitsu Szitsu.html" {
+		t.Errorf("expected a redirect at the old path, got %q, %v", path, ok)
+	}
+	if _, ok := legacyPlayerPath(dir, "alice", slugs); ok {
+		t.Error("names that are already slugs need no redirect")
+	}
+	if _, ok := legacyPlayerPath(dir, "Alice", slugs); ok {
+		t.Error("the old path of Alice is the page of alice on case-insensitive file systems")
+	}
+	if _, ok := legacyPlayerPath(dir, "AC/DC", slugs); ok {
+		t.Error("names that were never valid file names need no redirect")
+	}
+}
+
+func TestPlayerSet(t *testing.T) {
+	p := newTestProcessor(t, map[int]string{1: "2024-08-31"})
+	writePending(t, p, "Matches-tournament-1.json", v2Export(
+		testMatch{Round: 1, Player1: "Alice", Player1Wins: 2, Player2: "Bob", Player2Wins: 1},
+	))
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	a := &app{cfg: p.config, store: p.store}
+
+	if err := runPlayerSet(a, []string{"-region", "Paris", "-links", "https://a.example, https://b.example", "alice"}); err != nil {
+		t.Fatalf("player set failed: %v", err)
+	}
+	if err := runPlayerSet(a, []string{"-opt-out", "Alice"}); err != nil {
+		t.Fatalf("player set failed: %v", err)
+	}
+	alice, _ := p.store.FindPlayer("Alice")
+	profile, _ := p.store.GetPlayerProfile(alice.ID)
+	if profile == nil || profile.Region != "Paris" || len(profile.Links) != 2 || !profile.OptOut {
+		t.Errorf("expected earlier fields to be kept, got %+v", profile)
+	}
+	if _, err := findPlayer(p.store, "Alice"); err == nil {
+		t.Error("expected Alice to be hidden from public lookups")
+	}
+
+	if err := runPlayerSet(a, []string{"Dave"}); err == nil {
+		t.Error("expected error for unknown player")
+	}
+}
+
+func TestTournamentSetDate(t *testing.T) {
+	p := newTestProcessor(t, map[int]string{1: "2024-08-01", 2: "2024-08-08"})
+	p.config.Processing.NonInteractive = true
+	writePending(t, p, "Matches-tournament-1.json", v2Export(
+		testMatch{Round: 1, Player1: "Alice", Player1Wins: 2, Player2: "Bob", Player2Wins: 0},
+	))
+	writePending(t, p, "Matches-tournament-2.json", v2Export(
+		testMatch{Round: 1, Player1: "Bob", Player1Wins: 2, Player2: "Carol", Player2Wins: 0},
+	))
+	writePending(t, p, "Matches-tournament-3.json", v2Export(
+		testMatch{Round: 1, Player1: "Carol", Player1Wins: 2, Player2: "Alice", Player2Wins: 1},
+	))
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	a := &app{cfg: p.config, store: p.store}
+
+	// Releasing a parked tournament ingests and rates it
+	if err := runTournament(a, []string{"set-date", "3", "2024-08-15"}); err != nil {
+		t.Fatalf("tournament set-date failed: %v", err)
+	}
+	if tournament, _ := p.store.GetTournamentByMeleeID(3); tournament == nil || !tournament.Rated {
+		t.Fatalf("expected tournament 3 to be rated, got %+v", tournament)
+	}
+	if _, err := os.Stat(filepath.Join(p.config.Paths.ProcessedDir, "Matches-tournament-3.json")); err != nil {
+		t.Errorf("expected released file to be processed: %v", err)
+	}
+
+	// Moving a rated tournament replays every tournament in the new order
+	if err := runTournament(a, []string{"set-date", "1", "2024-08-20"}); err != nil {
+		t.Fatalf("tournament set-date failed: %v", err)
+	}
+	unrated, _ := p.store.GetUnratedTournaments()
+	if len(unrated) != 0 {
+		t.Errorf("expected every tournament to be rated again, got %+v", unrated)
+	}
+	history, _ := p.store.GetPlayerMatchHistory("Alice")
+	if len(history) != 2 || history[0].TournamentID != 3 || history[1].PlayerELOBefore == 1500 {
+		t.Errorf("expected Alice's sets replayed in the new order, got %+v", history)
+	}
+}
+
+func TestRenderRemovesOptedOutPlayer(t *testing.T) {
+	p := newTestProcessor(t, map[int]string{1: "2024-08-31"})
+	writePending(t, p, "Matches-tournament-1.json", v2Export(
+		testMatch{Round: 1, Player1: "Alice Liddell", Player1Wins: 2, Player2: "Bob", Player2Wins: 1},
+		testMatch{Round: 2, Player1: "Carol", Player1Wins: 2, Player2: "Alice Liddell", Player2Wins: 0},
+		testMatch{Round: 3, Player1: "Bob", Player1Wins: 2, Player2: "Carol", Player2Wins: 1},
+	))
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	p.config.Output.MinMatches = 1
+
+	// render writes docs/ relative to the working directory
+	root := t.TempDir()
+	wd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	p.config.Paths.Output = "docs/index.html"
+
+	// Alice is the only player from her region
+	a := &app{cfg: p.config, store: p.store}
+	if err := runPlayerSet(a, []string{"-region", "Wonderland", "Alice Liddell"}); err != nil {
+		t.Fatalf("player set failed: %v", err)
+	}
+	if err := render(p.store, p.config); err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	for _, path := range []string{"docs/players/Alice Liddell.html", "docs/regions/wonderland.html"} {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("expected Alice's pages before she opts out: %v", err)
+		}
+	}
+
+	if err := runPlayerSet(a, []string{"-opt-out", "Alice Liddell"}); err != nil {
+		t.Fatalf("player set failed: %v", err)
+	}
+	if err := render(p.store, p.config); err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	err := filepath.WalkDir("docs", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, name := range []string{"Alice", "alice"} {
+			if strings.Contains(path, name) || strings.Contains(string(data), name) {
+				t.Errorf("expected %s to be anonymized, found %q in %s", name, name, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk docs: %v", err)
+	}
+	if _, err := os.Stat("docs/players/bob.html"); err != nil {
+		t.Errorf("expected other players' pages to be kept: %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/melee-elo-ranking/internal/parser"
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+// hashBytes returns the same hash as hashFile for data already in memory.
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hashFile returns the hex-encoded SHA-256 of a file's contents.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// matchChange pairs a stored match with the incoming version that replaces it.
+type matchChange struct {
+	Old storage.Match
+	New parser.Match
+}
+
+// matchDiff describes how a re-ingested tournament file differs from the
+// matches already stored for that tournament.
+type matchDiff struct {
+	Added   []parser.Match
+	Removed []storage.Match
+	Changed []matchChange
+}
+
+func (d matchDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// diffMatches compares stored matches with the matches parsed from a new file,
+// keyed by match ID. A match counts as changed when its round, players or
+// score differ.
+func diffMatches(stored []storage.Match, incoming []parser.Match) matchDiff {
+	var diff matchDiff
+
+	storedByID := make(map[string]storage.Match, len(stored))
+	for _, m := range stored {
+		storedByID[m.ID] = m
+	}
+
+	seen := make(map[string]bool, len(incoming))
+	for _, m := range incoming {
+		seen[m.ID] = true
+		old, ok := storedByID[m.ID]
+		if !ok {
+			diff.Added = append(diff.Added, m)
+			continue
+		}
+		if matchChanged(old, m) {
+			diff.Changed = append(diff.Changed, matchChange{Old: old, New: m})
+		}
+	}
+
+	for _, m := range stored {
+		if !seen[m.ID] {
+			diff.Removed = append(diff.Removed, m)
+		}
+	}
+
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].ID < diff.Added[j].ID })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].ID < diff.Removed[j].ID })
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].Old.ID < diff.Changed[j].Old.ID })
+
+	return diff
+}
+
+func matchChanged(old storage.Match, m parser.Match) bool {
+	if len(m.Competitors) < 2 {
+		return true
+	}
+	c1 := m.Competitors[0]
+	c2 := m.Competitors[1]
+	return old.Round != m.RoundNumber ||
+		old.Player1ExternalID != c1.Player.ID ||
+		old.Player2ExternalID != c2.Player.ID ||
+		old.Player1Wins != c1.GameWins ||
+		old.Player2Wins != c2.GameWins
+}
+
+// Print writes a human-readable report of the diff.
+func (d matchDiff) Print(w io.Writer, tournamentID int) {
+	fmt.Fprintf(w, "Tournament %d differs from the stored version: %d added, %d removed, %d changed\n",
+		tournamentID, len(d.Added), len(d.Removed), len(d.Changed))
+	for _, m := range d.Added {
+		fmt.Fprintf(w, "  + %s\n", describeParsedMatch(m))
+	}
+	for _, m := range d.Removed {
+		fmt.Fprintf(w, "  - %s round %d: %d-%d\n", m.ID, m.Round, m.Player1Wins, m.Player2Wins)
+	}
+	for _, c := range d.Changed {
+		fmt.Fprintf(w, "  ~ %s round %d: %d-%d -> %s\n", c.Old.ID, c.Old.Round, c.Old.Player1Wins, c.Old.Player2Wins, describeParsedMatch(c.New))
+	}
+}
+
+func describeParsedMatch(m parser.Match) string {
+	if len(m.Competitors) < 2 {
+		return fmt.Sprintf("%s round %d", m.ID, m.RoundNumber)
+	}
+	c1 := m.Competitors[0]
+	c2 := m.Competitors[1]
+	return fmt.Sprintf("%s round %d: %s %d-%d %s", m.ID, m.RoundNumber,
+		c1.Player.DisplayName, c1.GameWins, c2.GameWins, c2.Player.DisplayName)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/melee-elo-ranking/internal/parser"
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+func parsedMatch(id string, round int, p1 int64, w1 int, p2 int64, w2 int) parser.Match {
+	return parser.Match{
+		ID:          id,
+		RoundNumber: round,
+		Competitors: []parser.Competitor{
+			{Player: parser.Player{ID: p1}, GameWins: w1},
+			{Player: parser.Player{ID: p2}, GameWins: w2},
+		},
+	}
+}
+
+func TestDiffMatches(t *testing.T) {
+	stored := []storage.Match{
+		{ID: "same", Round: 1, Player1ExternalID: 1, Player2ExternalID: 2, Player1Wins: 2, Player2Wins: 0},
+		{ID: "changed", Round: 1, Player1ExternalID: 3, Player2ExternalID: 4, Player1Wins: 2, Player2Wins: 1},
+		{ID: "removed", Round: 2, Player1ExternalID: 1, Player2ExternalID: 3, Player1Wins: 0, Player2Wins: 2},
+	}
+	incoming := []parser.Match{
+		parsedMatch("same", 1, 1, 2, 2, 0),
+		parsedMatch("changed", 1, 3, 1, 4, 2),
+		parsedMatch("added", 2, 2, 2, 4, 0),
+	}
+
+	diff := diffMatches(stored, incoming)
+
+	if len(diff.Added) != 1 || diff.Added[0].ID != "added" {
+		t.Errorf("expected one added match, got %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].ID != "removed" {
+		t.Errorf("expected one removed match, got %+v", diff.Removed)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].Old.ID != "changed" {
+		t.Errorf("expected one changed match, got %+v", diff.Changed)
+	}
+}
+
+func TestDiffMatchesIdentical(t *testing.T) {
+	stored := []storage.Match{
+		{ID: "a", Round: 1, Player1ExternalID: 1, Player2ExternalID: 2, Player1Wins: 2, Player2Wins: 0},
+	}
+	incoming := []parser.Match{parsedMatch("a", 1, 1, 2, 2, 0)}
+
+	if diff := diffMatches(stored, incoming); !diff.Empty() {
+		t.Errorf("expected empty diff, got %+v", diff)
+	}
+}
+
+func TestProcessSkipsIdenticalFile(t *testing.T) {
+	p := newTestProcessor(t, map[int]string{1: "2024-08-31"})
+	export := v2Export(
+		testMatch{Round: 1, Player1: "Alice", Player1Wins: 2, Player2: "Bob", Player2Wins: 0},
+	)
+
+	writePending(t, p, "Matches-tournament-1.json", export)
+	if err := p.Process(); err != nil {
+		t.Fatalf("first Process failed: %v", err)
+	}
+
+	// Same export dropped in again under a different name.
+	writePending(t, p, "redownload-Matches-tournament-1.json", export)
+	if err := p.Process(); err != nil {
+		t.Fatalf("second Process failed: %v", err)
+	}
+
+	tournament, err := p.store.GetTournamentByMeleeID(1)
+	if err != nil || tournament == nil {
+		t.Fatalf("expected tournament to exist: %v", err)
+	}
+	if tournament.SourceFilename != "Matches-tournament-1.json" {
+		t.Errorf("expected source filename to be kept, got %s", tournament.SourceFilename)
+	}
+	if tournament.ContentHash == "" {
+		t.Error("expected content hash to be stored")
+	}
+
+	pending, _ := os.ReadDir(p.config.Paths.PendingDir)
+	if len(pending) != 0 {
+		t.Errorf("expected duplicate to leave pending, found %d files", len(pending))
+	}
+	if _, err := os.Stat(filepath.Join(p.config.Paths.ProcessedDir, "redownload-Matches-tournament-1.json")); err != nil {
+		t.Errorf("expected duplicate in processed dir: %v", err)
+	}
+}
+
+func TestProcessAppliesChangedFile(t *testing.T) {
+	p := newTestProcessor(t, map[int]string{1: "2024-08-31"})
+
+	writePending(t, p, "Matches-tournament-1.json", v2Export(
+		testMatch{Round: 1, Player1: "Alice", Player1Wins: 2, Player2: "Bob", Player2Wins: 0},
+		testMatch{Round: 1, Player1: "Carol", Player1Wins: 2, Player2: "Dave", Player2Wins: 1},
+	))
+	if err := p.Process(); err != nil {
+		t.Fatalf("first Process failed: %v", err)
+	}
+
+	// Corrected export: Alice-Bob result flipped, Carol-Dave dropped,
+	// a second round added.
+	writePending(t, p, "Matches-tournament-1.json", v2Export(
+		testMatch{Round: 1, Player1: "Alice", Player1Wins: 0, Player2: "Bob", Player2Wins: 2},
+		testMatch{Round: 2, Player1: "Alice", Player1Wins: 2, Player2: "Carol", Player2Wins: 0},
+	))
+	if err := p.Process(); err != nil {
+		t.Fatalf("second Process failed: %v", err)
+	}
+
+	matches, err := p.store.GetTournamentMatches(1)
+	if err != nil {
+		t.Fatalf("failed to get matches: %v", err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches after update, got %d", len(matches))
+	}
+	if matches[0].Player1Wins != 0 || matches[0].Player2Wins != 2 {
+		t.Errorf("expected corrected score 0-2, got %d-%d", matches[0].Player1Wins, matches[0].Player2Wins)
+	}
+	if matches[1].Round != 2 {
+		t.Errorf("expected added round 2 match, got round %d", matches[1].Round)
+	}
+}
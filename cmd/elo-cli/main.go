@@ -8,23 +8,38 @@ import (
 	"strings"
 
 	"github.com/melee-elo-ranking/internal/config"
-	"github.com/melee-elo-ranking/internal/elo"
-	"github.com/melee-elo-ranking/internal/generator"
-	"github.com/melee-elo-ranking/internal/melee"
-	"github.com/melee-elo-ranking/internal/parser"
 	"github.com/melee-elo-ranking/internal/storage"
 )
 
-var tournamentDates = flag.String("dates", "", "Tournament dates in format: 170676=2024-08-31,172453=2024-10-17")
+var configPath = flag.String("config", "config.json", "Path to the config file; ELO_* environment variables override its fields")
+var dbPath = flag.String("db", "", "Path to the SQLite database, overriding paths.database from the config")
 
 func main() {
+	flag.Usage = usage
 	flag.Parse()
 
+	name, args := "run", flag.Args()
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	cmd, ok := findCommand(name)
+	if !ok {
+		fmt.Fprintf(flag.CommandLine.Output(), "Unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
+	}
+
 	// Load configuration
-	cfg, err := config.Load("config.json")
+	cfg, err := config.Load(*configPath)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	if *dbPath != "" {
+		cfg.Paths.Database = *dbPath
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
 
 	// Ensure directories exist
 	ensureDirs(cfg)
@@ -34,77 +49,11 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to initialize storage: %v", err)
 	}
-	defer store.Close()
-
-	// Create ELO calculator
-	calculator := elo.New(cfg.ELO.InitialRating)
-
-	// Create parser
-	matchParser := parser.New()
-
-	// Create melee client for fetching tournament dates from melee.gg
-	meleeClient := melee.NewClient()
-
-	// Parse tournament dates from flag
-	datesMap := parseTournamentDates(*tournamentDates)
-
-	// Process pending matches
-	processor := NewProcessor(store, calculator, matchParser, meleeClient, datesMap, cfg)
-	if err := processor.Process(); err != nil {
-		log.Fatalf("Failed to process matches: %v", err)
-	}
 
-	// Generate rankings
-	rankings, err := store.GetRankings()
+	err = cmd.run(&app{cfg: cfg, store: store}, args)
+	store.Close()
 	if err != nil {
-		log.Fatalf("Failed to get rankings: %v", err)
-	}
-
-	// Generate HTML
-	gen := generator.New(cfg.Output.Title, cfg.Output.Description)
-	if err := gen.Generate(rankings, cfg.Paths.Output); err != nil {
-		log.Fatalf("Failed to generate HTML: %v", err)
-	}
-
-	// Generate player detail pages
-	playersDir := "docs/players"
-	if err := os.MkdirAll(playersDir, 0755); err != nil {
-		log.Fatalf("Failed to create players directory: %v", err)
-	}
-
-	for _, r := range rankings {
-		matches, err := store.GetPlayerMatchHistory(r.DisplayName)
-		if err != nil {
-			log.Printf("Warning: Failed to get match history for %s: %v", r.DisplayName, err)
-			continue
-		}
-
-		playerPath := playersDir + "/" + r.DisplayName + ".html"
-		if err := gen.GeneratePlayerPage(r.DisplayName, matches, r, playerPath); err != nil {
-			log.Printf("Warning: Failed to generate player page for %s: %v", r.DisplayName, err)
-			continue
-		}
-	}
-
-	log.Println("Successfully generated rankings at", cfg.Paths.Output)
-	log.Println("Generated player pages in", playersDir)
-
-	// Generate matchup matrix
-	matchups, err := store.GetMatchups()
-	if err != nil {
-		log.Printf("Warning: Failed to get matchups: %v", err)
-	} else {
-		playerNames := make([]string, len(rankings))
-		for i, r := range rankings {
-			playerNames[i] = r.DisplayName
-		}
-
-		matchupPath := "docs/matchups.html"
-		if err := gen.GenerateMatchupMatrix(matchups, playerNames, matchupPath); err != nil {
-			log.Printf("Warning: Failed to generate matchup matrix: %v", err)
-		} else {
-			log.Println("Generated matchup matrix at", matchupPath)
-		}
+		log.Fatalf("%s: %v", name, err)
 	}
 }
 
@@ -113,6 +62,7 @@ func ensureDirs(cfg *config.Config) {
 		cfg.Paths.PendingDir,
 		cfg.Paths.ProcessedDir,
 		cfg.Paths.FailedDir,
+		cfg.Paths.NeedsDateDir,
 		"docs",
 	}
 	for _, dir := range dirs {
@@ -2,17 +2,22 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/melee-elo-ranking/internal/config"
 	"github.com/melee-elo-ranking/internal/elo"
+	"github.com/melee-elo-ranking/internal/manifest"
 	"github.com/melee-elo-ranking/internal/melee"
 	"github.com/melee-elo-ranking/internal/parser"
 	"github.com/melee-elo-ranking/internal/storage"
@@ -25,6 +30,13 @@ type Processor struct {
 	meleeClient     *melee.Client
 	tournamentDates map[int]string
 	config          *config.Config
+	manifest        manifest.Manifest
+	dryRun          bool
+	uploadMu        sync.Mutex
+
+	// needsFullRebuild is set when matches of an already stored tournament
+	// change, so ratings can't be brought up to date incrementally.
+	needsFullRebuild bool
 }
 
 func NewProcessor(store *storage.Storage, calc *elo.Calculator, parser *parser.Parser, meleeClient *melee.Client, tournamentDates map[int]string, cfg *config.Config) *Processor {
@@ -38,42 +50,78 @@ func NewProcessor(store *storage.Storage, calc *elo.Calculator, parser *parser.P
 	}
 }
 
+// SetDryRun makes the processor leave pending files where they are. It is
+// meant to be used with a store returned by storage.CopyToMemory.
+func (p *Processor) SetDryRun(dryRun bool) {
+	p.dryRun = dryRun
+}
+
+// Process ingests pending files and brings ratings up to date.
 func (p *Processor) Process() error {
+	if err := p.Ingest(); err != nil {
+		return err
+	}
+	return p.rebuild()
+}
+
+// Ingest stores the matches from pending files without touching ratings.
+func (p *Processor) Ingest() error {
 	files, err := os.ReadDir(p.config.Paths.PendingDir)
 	if err != nil {
 		return fmt.Errorf("failed to read pending directory: %w", err)
 	}
 
-	if len(files) == 0 {
+	var filenames []string
+	for _, file := range files {
+		if file.IsDir() || manifest.IsSidecar(file.Name()) {
+			continue
+		}
+		filenames = append(filenames, file.Name())
+	}
+	return p.ingestFiles(filenames)
+}
+
+// ingestFiles stores the matches from the named files in the pending dir.
+func (p *Processor) ingestFiles(filenames []string) error {
+	var err error
+	p.manifest, err = manifest.Load(p.config.Paths.Manifest)
+	if err != nil {
+		return fmt.Errorf("failed to load tournament manifest: %w", err)
+	}
+
+	if len(filenames) == 0 {
 		fmt.Println("No pending files to process")
-		return p.fullRebuild()
+		return nil
 	}
 
 	type tournamentFile struct {
 		tournamentID int
 		matches      []parser.Match
 		filename     string
+		contentHash  string
 	}
 
 	var tournamentFiles []tournamentFile
 
-	for _, file := range files {
-		if file.IsDir() {
+	for _, filename := range filenames {
+		tournamentID, err := extractTournamentID(filename)
+		if err != nil {
+			fmt.Printf("Warning: failed to extract tournament ID from %s: %v\n", filename, err)
+			p.moveToFailed(filename)
 			continue
 		}
 
-		tournamentID, err := extractTournamentID(file.Name())
+		path := filepath.Join(p.config.Paths.PendingDir, filename)
+		matches, err := p.parser.ParseFile(path, tournamentID)
 		if err != nil {
-			fmt.Printf("Warning: failed to extract tournament ID from %s: %v\n", file.Name(), err)
-			p.moveToFailed(file.Name())
+			fmt.Printf("Warning: failed to parse %s: %v\n", filename, err)
+			p.moveToFailed(filename)
 			continue
 		}
 
-		filepath := filepath.Join(p.config.Paths.PendingDir, file.Name())
-		matches, err := p.parser.ParseFile(filepath, tournamentID)
+		contentHash, err := hashFile(path)
 		if err != nil {
-			fmt.Printf("Warning: failed to parse %s: %v\n", file.Name(), err)
-			p.moveToFailed(file.Name())
+			fmt.Printf("Warning: failed to hash %s: %v\n", filename, err)
 			continue
 		}
 
@@ -81,20 +129,50 @@ func (p *Processor) Process() error {
 			tournamentFiles = append(tournamentFiles, tournamentFile{
 				tournamentID: tournamentID,
 				matches:      matches,
-				filename:     file.Name(),
+				filename:     filename,
+				contentHash:  contentHash,
 			})
 		}
 	}
 
 	if len(tournamentFiles) == 0 {
 		fmt.Println("No valid matches found in pending files")
-		return p.fullRebuild()
+		return nil
 	}
 
 	newTournaments := 0
 	for _, tf := range tournamentFiles {
+		duplicate, err := p.store.GetTournamentByContentHash(tf.contentHash)
+		if err != nil {
+			fmt.Printf("Warning: failed to look up content hash of %s: %v\n", tf.filename, err)
+			continue
+		}
+		if duplicate != nil {
+			fmt.Printf("Skipping %s: identical to %s already ingested for tournament %d\n", tf.filename, duplicate.SourceFilename, duplicate.MeleeID)
+			if err := p.moveToProcessed(tf.filename); err != nil {
+				fmt.Printf("Warning: failed to move file %s: %v\n", tf.filename, err)
+			}
+			continue
+		}
+
+		existing, err := p.store.GetTournamentByMeleeID(tf.tournamentID)
+		if err != nil {
+			fmt.Printf("Warning: failed to get tournament %d: %v\n", tf.tournamentID, err)
+			continue
+		}
+
+		meta, err := p.tournamentMetadata(tf.tournamentID, tf.filename)
+		if err != nil {
+			fmt.Printf("Warning: failed to read metadata for tournament %d: %v\n", tf.tournamentID, err)
+			p.moveToFailed(tf.filename)
+			continue
+		}
+		metaDate, err := meta.ParsedDate()
+		if err != nil {
+			fmt.Printf("Warning: ignoring manifest date for tournament %d: %v\n", tf.tournamentID, err)
+		}
+
 		var tournamentDate time.Time
-		var err error
 
 		// Check if date was provided via flag
 		if dateStr, ok := p.tournamentDates[tf.tournamentID]; ok {
@@ -102,9 +180,16 @@ func (p *Processor) Process() error {
 			if err != nil {
 				fmt.Printf("Warning: invalid date format for tournament %d: %v\n", tf.tournamentID, err)
 			}
+			if !metaDate.IsZero() && !metaDate.Equal(tournamentDate) {
+				fmt.Printf("Conflict for tournament %d: date is %s in the manifest but %s in -dates, using -dates\n",
+					tf.tournamentID, meta.Date, dateStr)
+			}
+		} else if !metaDate.IsZero() {
+			// Manifest and sidecar dates win over stored and scraped ones
+			tournamentDate = metaDate
+			p.checkScrapedDate(tf.tournamentID, existing, metaDate)
 		} else {
 			// Try to get from existing tournament
-			existing, _ := p.store.GetTournamentByMeleeID(tf.tournamentID)
 			if existing != nil && !existing.Date.IsZero() {
 				tournamentDate = existing.Date
 			} else {
@@ -112,12 +197,21 @@ func (p *Processor) Process() error {
 				if p.meleeClient != nil {
 					fetched, fetchErr := p.meleeClient.FetchTournamentDate(tf.tournamentID)
 					if fetchErr == nil && !fetched.IsZero() {
-						tournamentDate = fetched
+						tournamentDate = dayOf(fetched)
 						fmt.Printf("Fetched date for tournament %d from melee.gg: %s\n", tf.tournamentID, tournamentDate.Format("2006-01-02"))
 					} else if fetchErr != nil {
 						fmt.Printf("Could not fetch date for tournament %d: %v\n", tf.tournamentID, fetchErr)
 					}
 				}
+				// In non-interactive mode park the file until a date is set
+				if tournamentDate.IsZero() && p.config.Processing.NonInteractive {
+					fmt.Printf("No date for tournament %d, moving %s to %s (release with: elo-cli tournament set-date %d YYYY-MM-DD)\n",
+						tf.tournamentID, tf.filename, p.config.Paths.NeedsDateDir, tf.tournamentID)
+					if err := p.moveToNeedsDate(tf.filename); err != nil {
+						fmt.Printf("Warning: failed to move file %s: %v\n", tf.filename, err)
+					}
+					continue
+				}
 				// If still no date, prompt user
 				if tournamentDate.IsZero() {
 					tournamentDate, err = promptForTournamentDate(tf.tournamentID)
@@ -135,8 +229,29 @@ func (p *Processor) Process() error {
 			fmt.Printf("Warning: failed to create tournament %d: %v\n", tf.tournamentID, err)
 			continue
 		}
+		if existing != nil {
+			p.updateStoredDate(*existing, tournamentDate)
+		}
+		if err := p.applyMetadata(tf.tournamentID, existing, meta); err != nil {
+			fmt.Printf("Warning: failed to store metadata for tournament %d: %v\n", tf.tournamentID, err)
+		}
 		newTournaments++
 
+		// A file for a tournament we already have: report what changed and
+		// apply corrections and removals before adding new matches.
+		stored, err := p.store.GetTournamentMatches(tf.tournamentID)
+		if err != nil {
+			fmt.Printf("Warning: failed to load stored matches for tournament %d: %v\n", tf.tournamentID, err)
+			continue
+		}
+		if len(stored) > 0 {
+			diff := diffMatches(stored, tf.matches)
+			if !diff.Empty() {
+				diff.Print(os.Stdout, tf.tournamentID)
+				p.applyDiff(tf.tournamentID, diff)
+			}
+		}
+
 		for _, match := range tf.matches {
 			exists, err := p.store.MatchExists(match.ID)
 			if err != nil {
@@ -147,36 +262,21 @@ func (p *Processor) Process() error {
 				continue
 			}
 
-			c1 := match.Competitors[0]
-			c2 := match.Competitors[1]
-
-			player1, err := p.store.GetOrCreatePlayer(c1.Player.ID, c1.Player.DisplayName, c1.Player.Username)
-			if err != nil {
-				fmt.Printf("Warning: failed to get/create player %s: %v\n", c1.Player.DisplayName, err)
-				continue
-			}
-			player2, err := p.store.GetOrCreatePlayer(c2.Player.ID, c2.Player.DisplayName, c2.Player.Username)
+			storageMatch, err := p.toStorageMatch(tf.tournamentID, match)
 			if err != nil {
-				fmt.Printf("Warning: failed to get/create player %s: %v\n", c2.Player.DisplayName, err)
+				fmt.Printf("Warning: %v\n", err)
 				continue
 			}
 
-			storageMatch := storage.Match{
-				ID:           match.ID,
-				TournamentID: tf.tournamentID,
-				Round:        match.RoundNumber,
-				Player1ID:    player1.ID,
-				Player2ID:    player2.ID,
-				Player1Wins:  c1.GameWins,
-				Player2Wins:  c2.GameWins,
-				DatePlayed:   match.DateCreated,
-			}
-
 			if err := p.store.SaveMatch(storageMatch); err != nil {
 				fmt.Printf("Warning: failed to save match %s: %v\n", match.ID, err)
 			}
 		}
 
+		if err := p.store.UpdateTournamentSource(tf.tournamentID, tf.contentHash, tf.filename); err != nil {
+			fmt.Printf("Warning: failed to record source of tournament %d: %v\n", tf.tournamentID, err)
+		}
+
 		if err := p.moveToProcessed(tf.filename); err != nil {
 			fmt.Printf("Warning: failed to move file %s: %v\n", tf.filename, err)
 		}
@@ -184,7 +284,150 @@ func (p *Processor) Process() error {
 
 	fmt.Printf("Processed %d new tournaments\n", newTournaments)
 
-	return p.fullRebuild()
+	return nil
+}
+
+// tournamentMetadata combines the manifest entry of a tournament with the
+// sidecar next to its export file. Sidecar values win; fields set to
+// different values in both are reported.
+func (p *Processor) tournamentMetadata(tournamentID int, filename string) (manifest.Metadata, error) {
+	meta := p.manifest[tournamentID]
+
+	sidecar, found, err := manifest.LoadSidecar(filepath.Join(p.config.Paths.PendingDir, filename))
+	if err != nil {
+		return manifest.Metadata{}, err
+	}
+	if !found {
+		return meta, nil
+	}
+
+	merged, conflicts := manifest.Merge(meta, sidecar)
+	for _, c := range conflicts {
+		fmt.Printf("Conflict for tournament %d: %s is %q in the manifest but %q in the sidecar, using the sidecar\n",
+			tournamentID, c.Field, c.Base, c.Override)
+	}
+	return merged, nil
+}
+
+// checkScrapedDate reports a manifest date that disagrees with the date on
+// melee.gg. Stored dates are compared by updateStoredDate, so only
+// tournaments without one are looked up.
+func (p *Processor) checkScrapedDate(tournamentID int, existing *storage.Tournament, date time.Time) {
+	if p.meleeClient == nil || (existing != nil && !existing.Date.IsZero()) {
+		return
+	}
+	fetched, err := p.meleeClient.FetchTournamentDate(tournamentID)
+	if err != nil {
+		fmt.Printf("Could not fetch date for tournament %d: %v\n", tournamentID, err)
+		return
+	}
+	if scraped := fetched.Format("2006-01-02"); scraped != date.Format("2006-01-02") {
+		fmt.Printf("Conflict for tournament %d: date is %s in the manifest but %s on melee.gg, using the manifest\n",
+			tournamentID, date.Format("2006-01-02"), scraped)
+	}
+}
+
+// updateStoredDate overwrites the stored date of an existing tournament when
+// it was resolved differently this time, e.g. a manifest correcting a scraped
+// date.
+func (p *Processor) updateStoredDate(existing storage.Tournament, date time.Time) {
+	// Dates are compared by day: a date scraped with its start time names the
+	// same day as the manifest
+	if date.IsZero() || existing.Date.Format("2006-01-02") == date.Format("2006-01-02") {
+		return
+	}
+	if !existing.Date.IsZero() {
+		fmt.Printf("Conflict for tournament %d: stored date is %s, using %s\n",
+			existing.MeleeID, existing.Date.Format("2006-01-02"), date.Format("2006-01-02"))
+	}
+	// Moving a rated tournament unrates them all, forcing a full rebuild
+	if err := p.store.UpdateTournamentDate(existing.MeleeID, date); err != nil {
+		fmt.Printf("Warning: failed to update date of tournament %d: %v\n", existing.MeleeID, err)
+	}
+}
+
+// dayOf drops the start time melee.gg pages carry, so scraped dates are
+// stored like manifest dates.
+func dayOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// applyMetadata stores the name, tier, location and organizer of a
+// tournament. Unset fields keep their stored values; changed ones are
+// reported.
+func (p *Processor) applyMetadata(tournamentID int, existing *storage.Tournament, meta manifest.Metadata) error {
+	var stored manifest.Metadata
+	if existing != nil {
+		stored = manifest.Metadata{
+			Name:      existing.Name,
+			Tier:      existing.Tier,
+			Location:  existing.Location,
+			Organizer: existing.Organizer,
+		}
+	}
+	meta.Date = ""
+
+	merged, conflicts := manifest.Merge(stored, meta)
+	if merged == stored {
+		return nil
+	}
+	for _, c := range conflicts {
+		fmt.Printf("Conflict for tournament %d: stored %s is %q, using %q\n", tournamentID, c.Field, c.Base, c.Override)
+	}
+	return p.store.UpdateTournamentMetadata(tournamentID, merged.Name, merged.Tier, merged.Location, merged.Organizer)
+}
+
+// toStorageMatch resolves the players of a parsed match and converts it into
+// a storage match.
+func (p *Processor) toStorageMatch(tournamentID int, match parser.Match) (storage.Match, error) {
+	c1 := match.Competitors[0]
+	c2 := match.Competitors[1]
+
+	player1, err := p.store.GetOrCreatePlayer(c1.Player.ID, c1.Player.DisplayName, c1.Player.Username)
+	if err != nil {
+		return storage.Match{}, fmt.Errorf("failed to get/create player %s: %w", c1.Player.DisplayName, err)
+	}
+	player2, err := p.store.GetOrCreatePlayer(c2.Player.ID, c2.Player.DisplayName, c2.Player.Username)
+	if err != nil {
+		return storage.Match{}, fmt.Errorf("failed to get/create player %s: %w", c2.Player.DisplayName, err)
+	}
+
+	return storage.Match{
+		ID:           match.ID,
+		TournamentID: tournamentID,
+		Round:        match.RoundNumber,
+		Player1ID:    player1.ID,
+		Player2ID:    player2.ID,
+		Player1Wins:  c1.GameWins,
+		Player2Wins:  c2.GameWins,
+		DatePlayed:   match.DateCreated,
+	}, nil
+}
+
+// applyDiff removes matches that are no longer in the tournament file and
+// overwrites the ones whose result changed. Added matches are saved by the
+// regular ingest loop.
+func (p *Processor) applyDiff(tournamentID int, diff matchDiff) {
+	// Any change to a stored tournament, including added matches, affects
+	// ratings that may already have been computed from it.
+	p.needsFullRebuild = true
+
+	for _, m := range diff.Removed {
+		if err := p.store.DeleteMatch(m.ID); err != nil {
+			fmt.Printf("Warning: failed to delete match %s: %v\n", m.ID, err)
+		}
+	}
+
+	for _, c := range diff.Changed {
+		storageMatch, err := p.toStorageMatch(tournamentID, c.New)
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			continue
+		}
+		if err := p.store.UpdateMatchResult(storageMatch); err != nil {
+			fmt.Printf("Warning: failed to update match %s: %v\n", c.New.ID, err)
+		}
+	}
 }
 
 func promptForTournamentDate(tournamentID int) (time.Time, error) {
@@ -225,101 +468,182 @@ func extractTournamentID(filename string) (int, error) {
 	return strconv.Atoi(matches[1])
 }
 
-func (p *Processor) fullRebuild() error {
-	fmt.Println("Performing full ELO rebuild...")
+func (p *Processor) moveToProcessed(filename string) error {
+	return p.moveFromPending(filename, p.config.Paths.ProcessedDir)
+}
 
-	if err := p.store.ResetAllPlayersELO(); err != nil {
-		return fmt.Errorf("failed to reset player ELOs: %w", err)
-	}
+func (p *Processor) moveToFailed(filename string) error {
+	return p.moveFromPending(filename, p.config.Paths.FailedDir)
+}
 
-	allMatches, err := p.store.GetAllMatchesSorted()
-	if err != nil {
-		return fmt.Errorf("failed to get matches: %w", err)
-	}
+func (p *Processor) moveToNeedsDate(filename string) error {
+	return p.moveFromPending(filename, p.config.Paths.NeedsDateDir)
+}
 
-	fmt.Printf("Processing %d matches in chronological order...\n", len(allMatches))
+// moveFromPending moves a pending file, and its sidecar if it has one, to
+// dir. Nothing is moved in dry-run mode.
+func (p *Processor) moveFromPending(filename, dir string) error {
+	if p.dryRun {
+		return nil
+	}
+	src := filepath.Join(p.config.Paths.PendingDir, filename)
+	if _, err := moveFile(src, filepath.Join(dir, filename)); err != nil {
+		return err
+	}
+	return moveSidecars(src, dir)
+}
 
-	for _, match := range allMatches {
-		if err := p.processMatchFromDB(match); err != nil {
-			fmt.Printf("Warning: failed to process match %s: %v\n", match.ID, err)
+// moveSidecars moves the sidecar files of export to dir.
+func moveSidecars(export, dir string) error {
+	for _, sidecar := range manifest.SidecarPaths(export) {
+		if _, err := os.Stat(sidecar); os.IsNotExist(err) {
+			continue
+		}
+		if _, err := moveFile(sidecar, filepath.Join(dir, filepath.Base(sidecar))); err != nil {
+			return err
 		}
 	}
-
-	fmt.Println("Full rebuild complete")
 	return nil
 }
 
-func (p *Processor) processMatchFromDB(match storage.Match) error {
-	player1, err := p.store.GetPlayerByID(match.Player1ID)
+// SetTournamentDate stores the date of a tournament and moves its files from
+// the needs-date queue back to pending, so the next Process picks them up.
+// It returns the number of files released.
+func (p *Processor) SetTournamentDate(meleeID int, date time.Time) (int, error) {
+	existing, err := p.store.GetTournamentByMeleeID(meleeID)
 	if err != nil {
-		return fmt.Errorf("failed to get player 1: %w", err)
+		return 0, fmt.Errorf("failed to get tournament %d: %w", meleeID, err)
 	}
-	player2, err := p.store.GetPlayerByID(match.Player2ID)
-	if err != nil {
-		return fmt.Errorf("failed to get player 2: %w", err)
+	if existing == nil {
+		if _, err := p.store.GetOrCreateTournament(meleeID, date); err != nil {
+			return 0, fmt.Errorf("failed to create tournament %d: %w", meleeID, err)
+		}
+	} else {
+		// Moving a rated tournament changes the replay order, so storage
+		// marks every tournament unrated until the next full rebuild.
+		if err := p.store.UpdateTournamentDate(meleeID, date); err != nil {
+			return 0, fmt.Errorf("failed to update date of tournament %d: %w", meleeID, err)
+		}
 	}
 
-	var winnerID *int64
-	if match.Player1Wins > match.Player2Wins {
-		winnerID = &match.Player1ID
-	} else if match.Player2Wins > match.Player1Wins {
-		winnerID = &match.Player2ID
+	files, err := os.ReadDir(p.config.Paths.NeedsDateDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read needs-date directory: %w", err)
 	}
 
-	newELO1, newELO2 := p.calculator.Calculate(
-		player1.CurrentELO,
-		player2.CurrentELO,
-		winnerID,
-		&match.Player1ID,
-		&match.Player2ID,
-		player1.MatchesPlayed,
-		player2.MatchesPlayed,
-	)
-
-	if err := p.store.UpdatePlayerELO(match.Player1ID, newELO1, match.Player1Wins > match.Player2Wins); err != nil {
-		return fmt.Errorf("failed to update player 1 ELO: %w", err)
-	}
-	if err := p.store.UpdatePlayerELO(match.Player2ID, newELO2, match.Player2Wins > match.Player1Wins); err != nil {
-		return fmt.Errorf("failed to update player 2 ELO: %w", err)
+	released := 0
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		id, err := extractTournamentID(file.Name())
+		if err != nil || id != meleeID {
+			continue
+		}
+		src := filepath.Join(p.config.Paths.NeedsDateDir, file.Name())
+		dst := filepath.Join(p.config.Paths.PendingDir, file.Name())
+		if _, err := moveFile(src, dst); err != nil {
+			return released, fmt.Errorf("failed to release %s: %w", file.Name(), err)
+		}
+		if err := moveSidecars(src, p.config.Paths.PendingDir); err != nil {
+			return released, fmt.Errorf("failed to release sidecar of %s: %w", file.Name(), err)
+		}
+		released++
 	}
+	return released, nil
+}
 
-	// Update match with ELO values
-	if err := p.store.UpdateMatchELO(match.ID, player1.CurrentELO, player2.CurrentELO, newELO1, newELO2); err != nil {
-		return fmt.Errorf("failed to update match ELO: %w", err)
+// moveFile moves src to dst and returns the path the file ended up at.
+// A rename is used where possible; across filesystems the file is copied,
+// synced and only then removed from src. An existing dst is never
+// overwritten: a numeric suffix is added instead (name-1.json, name-2.json).
+func moveFile(src, dst string) (string, error) {
+	dst, err := claimDestination(dst)
+	if err != nil {
+		return "", err
 	}
 
-	return nil
-}
-
-func (p *Processor) moveToProcessed(filename string) error {
-	src := filepath.Join(p.config.Paths.PendingDir, filename)
-	dst := filepath.Join(p.config.Paths.ProcessedDir, filename)
-	return moveFile(src, dst)
+	// dst is now an empty placeholder that only this move replaces
+	err = os.Rename(src, dst)
+	if errors.Is(err, syscall.EXDEV) {
+		err = copyAndRemove(src, dst)
+	}
+	if err != nil {
+		os.Remove(dst)
+		return "", err
+	}
+	return dst, nil
 }
 
-func (p *Processor) moveToFailed(filename string) error {
-	src := filepath.Join(p.config.Paths.PendingDir, filename)
-	dst := filepath.Join(p.config.Paths.FailedDir, filename)
-	return moveFile(src, dst)
+// claimDestination creates an empty file at dst, or at dst with a numeric
+// suffix before the extension if that name is taken. The file is created
+// exclusively, so concurrent moves (uploads run alongside the processor)
+// never claim the same name.
+func claimDestination(dst string) (string, error) {
+	ext := filepath.Ext(dst)
+	base := strings.TrimSuffix(dst, ext)
+
+	candidate := dst
+	for i := 1; ; i++ {
+		f, err := os.OpenFile(candidate, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			if err := f.Close(); err != nil {
+				os.Remove(candidate)
+				return "", err
+			}
+			return candidate, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
 }
 
-func moveFile(src, dst string) error {
+// copyAndRemove copies src into a temporary file next to dst, fsyncs it,
+// renames it into place and removes src. On failure no partial dst is left.
+func copyAndRemove(src, dst string) error {
 	sourceFile, err := os.Open(src)
 	if err != nil {
 		return err
 	}
 	defer sourceFile.Close()
 
-	destFile, err := os.Create(dst)
+	info, err := sourceFile.Stat()
 	if err != nil {
 		return err
 	}
-	defer destFile.Close()
 
-	_, err = io.Copy(destFile, sourceFile)
+	tmpFile, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*.tmp")
 	if err != nil {
 		return err
 	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := io.Copy(tmpFile, sourceFile); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Chmod(info.Mode().Perm()); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, dst); err != nil {
+		return err
+	}
+	sourceFile.Close()
 
-	return sourceFile.Close()
+	return os.Remove(src)
 }
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/melee-elo-ranking/internal/config"
+	"github.com/melee-elo-ranking/internal/elo"
+	"github.com/melee-elo-ranking/internal/parser"
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+// testConfig returns a config whose directories all live under a temp dir.
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	root := t.TempDir()
+	cfg := &config.Config{
+		ELO: config.ELOConfig{KFactor: 32, InitialRating: 1500},
+		Paths: config.PathsConfig{
+			PendingDir:   filepath.Join(root, "pending"),
+			ProcessedDir: filepath.Join(root, "processed"),
+			FailedDir:    filepath.Join(root, "failed"),
+			NeedsDateDir: filepath.Join(root, "needs-date"),
+			Manifest:     filepath.Join(root, "tournaments.yaml"),
+			Database:     filepath.Join(root, "rankings.db"),
+			Output:       filepath.Join(root, "index.html"),
+		},
+	}
+	for _, dir := range []string{cfg.Paths.PendingDir, cfg.Paths.ProcessedDir, cfg.Paths.FailedDir, cfg.Paths.NeedsDateDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", dir, err)
+		}
+	}
+	return cfg
+}
+
+// newTestProcessor returns a processor backed by a fresh database in a temp
+// dir. Tournament dates come from dates, so nothing is scraped or prompted.
+func newTestProcessor(t *testing.T, dates map[int]string) *Processor {
+	t.Helper()
+	cfg := testConfig(t)
+	store, err := storage.New(cfg.Paths.Database)
+	if err != nil {
+		t.Fatalf("failed to open storage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return NewProcessor(store, elo.New(cfg.ELO.InitialRating), parser.New(), nil, dates, cfg)
+}
+
+// testMatch is one set in a V2 melee export used by tests.
+type testMatch struct {
+	Round       int
+	Player1     string
+	Player1Wins int
+	Player2     string
+	Player2Wins int
+}
+
+// v2Export renders matches in the V2 melee export format.
+func v2Export(matches ...testMatch) string {
+	parts := make([]string, 0, len(matches))
+	for i, m := range matches {
+		parts = append(parts, fmt.Sprintf(
+			`{"RoundNumber":%d,"PhaseId":1,"TableNumber":%d,"Team1Id":%d,"Team1":%q,"Team1WinsAndByes":%d,"Team2Id":%d,"Team2":%q,"Team2WinsAndByes":%d,"HasResult":true,"ByeReason":null}`,
+			m.Round, i+1, testTeamID(m.Player1), m.Player1, m.Player1Wins, testTeamID(m.Player2), m.Player2, m.Player2Wins))
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+func testTeamID(name string) int {
+	id := 0
+	for _, c := range name {
+		id = 31*id + int(c)
+	}
+	return id
+}
+
+func writePending(t *testing.T, p *Processor, filename, content string) {
+	t.Helper()
+	writeTestFile(t, filepath.Join(p.config.Paths.PendingDir, filename), content)
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestMoveFileRemovesSource(t *testing.T) {
+	srcDir := t.TempDir()
+	dstDir := t.TempDir()
+
+	src := filepath.Join(srcDir, "Matches-tournament-1.json")
+	dst := filepath.Join(dstDir, "Matches-tournament-1.json")
+	writeTestFile(t, src, "[]")
+
+	got, err := moveFile(src, dst)
+	if err != nil {
+		t.Fatalf("moveFile failed: %v", err)
+	}
+
+	if got != dst {
+		t.Errorf("expected destination %s, got %s", dst, got)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("expected source to be removed, stat err: %v", err)
+	}
+	if content := readTestFile(t, dst); content != "[]" {
+		t.Errorf("expected destination content [], got %q", content)
+	}
+}
+
+func TestMoveFileCollision(t *testing.T) {
+	srcDir := t.TempDir()
+	dstDir := t.TempDir()
+
+	dst := filepath.Join(dstDir, "Matches-tournament-1.json")
+	writeTestFile(t, dst, "old")
+	writeTestFile(t, filepath.Join(dstDir, "Matches-tournament-1-1.json"), "older")
+
+	src := filepath.Join(srcDir, "Matches-tournament-1.json")
+	writeTestFile(t, src, "new")
+
+	got, err := moveFile(src, dst)
+	if err != nil {
+		t.Fatalf("moveFile failed: %v", err)
+	}
+
+	expected := filepath.Join(dstDir, "Matches-tournament-1-2.json")
+	if got != expected {
+		t.Errorf("expected destination %s, got %s", expected, got)
+	}
+	if content := readTestFile(t, dst); content != "old" {
+		t.Errorf("existing destination was overwritten: %q", content)
+	}
+	if content := readTestFile(t, got); content != "new" {
+		t.Errorf("expected moved content new, got %q", content)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("expected source to be removed, stat err: %v", err)
+	}
+}
+
+func TestMoveFileConcurrent(t *testing.T) {
+	srcDir := t.TempDir()
+	dstDir := t.TempDir()
+	dst := filepath.Join(dstDir, "Matches-tournament-1.json")
+
+	const moves = 8
+	var wg sync.WaitGroup
+	for i := 0; i < moves; i++ {
+		src := filepath.Join(srcDir, fmt.Sprintf("upload-%d.json", i))
+		writeTestFile(t, src, fmt.Sprint(i))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := moveFile(src, dst); err != nil {
+				t.Errorf("moveFile failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Every move must claim its own name instead of replacing another
+	seen := make(map[string]bool)
+	entries, _ := os.ReadDir(dstDir)
+	for _, entry := range entries {
+		seen[readTestFile(t, filepath.Join(dstDir, entry.Name()))] = true
+	}
+	if len(entries) != moves || len(seen) != moves {
+		t.Errorf("expected %d distinct files, got %d files with %d contents", moves, len(entries), len(seen))
+	}
+}
+
+func TestMoveFileMissingSource(t *testing.T) {
+	dstDir := t.TempDir()
+	dst := filepath.Join(dstDir, "Matches-tournament-1.json")
+
+	if _, err := moveFile(filepath.Join(t.TempDir(), "missing.json"), dst); err == nil {
+		t.Error("expected error for missing source")
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("expected no destination file, stat err: %v", err)
+	}
+}
+
+func TestCopyAndRemove(t *testing.T) {
+	srcDir := t.TempDir()
+	dstDir := t.TempDir()
+
+	src := filepath.Join(srcDir, "Matches-tournament-1.json")
+	dst := filepath.Join(dstDir, "Matches-tournament-1.json")
+	writeTestFile(t, src, "content")
+
+	if err := copyAndRemove(src, dst); err != nil {
+		t.Fatalf("copyAndRemove failed: %v", err)
+	}
+
+	if content := readTestFile(t, dst); content != "content" {
+		t.Errorf("expected destination content, got %q", content)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("expected source to be removed, stat err: %v", err)
+	}
+
+	entries, err := os.ReadDir(dstDir)
+	if err != nil {
+		t.Fatalf("failed to read destination dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the destination file, found %d entries", len(entries))
+	}
+}
+
+func TestCopyAndRemoveLeavesNoPartialFile(t *testing.T) {
+	srcDir := t.TempDir()
+	src := filepath.Join(srcDir, "Matches-tournament-1.json")
+	writeTestFile(t, src, "content")
+
+	// Destination directory does not exist, so the copy cannot start.
+	dst := filepath.Join(t.TempDir(), "missing", "Matches-tournament-1.json")
+	if err := copyAndRemove(src, dst); err == nil {
+		t.Fatal("expected error for missing destination directory")
+	}
+
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("expected no destination file, stat err: %v", err)
+	}
+	if content := readTestFile(t, src); content != "content" {
+		t.Errorf("expected source to be kept, got %q", content)
+	}
+}
+
+func TestMoveToProcessed(t *testing.T) {
+	cfg := testConfig(t)
+	writeTestFile(t, filepath.Join(cfg.Paths.PendingDir, "Matches-tournament-1.json"), "[]")
+
+	p := &Processor{config: cfg}
+	if err := p.moveToProcessed("Matches-tournament-1.json"); err != nil {
+		t.Fatalf("moveToProcessed failed: %v", err)
+	}
+
+	pending, _ := os.ReadDir(cfg.Paths.PendingDir)
+	if len(pending) != 0 {
+		t.Errorf("expected pending dir to be empty, found %d files", len(pending))
+	}
+	if _, err := os.Stat(filepath.Join(cfg.Paths.ProcessedDir, "Matches-tournament-1.json")); err != nil {
+		t.Errorf("expected file in processed dir: %v", err)
+	}
+}
+
+func TestDryRunLeavesFilesAndDatabase(t *testing.T) {
+	p := newTestProcessor(t, map[int]string{1: "2024-08-31"})
+	writePending(t, p, "Matches-tournament-1.json", v2Export(
+		testMatch{Round: 1, Player1: "Alice", Player1Wins: 2, Player2: "Bob", Player2Wins: 0},
+	))
+
+	if err := runDryRun(p.store, p.calculator, p.parser, nil, p.tournamentDates, p.config); err != nil {
+		t.Fatalf("runDryRun failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(p.config.Paths.PendingDir, "Matches-tournament-1.json")); err != nil {
+		t.Errorf("expected file to stay in pending: %v", err)
+	}
+	players, err := p.store.GetAllPlayers()
+	if err != nil {
+		t.Fatalf("failed to get players: %v", err)
+	}
+	if len(players) != 0 {
+		t.Errorf("expected database to stay empty, got %d players", len(players))
+	}
+	tournament, _ := p.store.GetTournamentByMeleeID(1)
+	if tournament != nil {
+		t.Errorf("expected no tournament in database, got %+v", tournament)
+	}
+}
+
+func TestNonInteractiveParksTournamentWithoutDate(t *testing.T) {
+	p := newTestProcessor(t, nil)
+	p.config.Processing.NonInteractive = true
+	writePending(t, p, "Matches-tournament-1.json", v2Export(
+		testMatch{Round: 1, Player1: "Alice", Player1Wins: 2, Player2: "Bob", Player2Wins: 0},
+	))
+
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(p.config.Paths.NeedsDateDir, "Matches-tournament-1.json")); err != nil {
+		t.Fatalf("expected file in needs-date queue: %v", err)
+	}
+	if tournament, _ := p.store.GetTournamentByMeleeID(1); tournament != nil {
+		t.Errorf("expected parked tournament not to be stored, got %+v", tournament)
+	}
+
+	released, err := p.SetTournamentDate(1, time.Date(2024, 8, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("SetTournamentDate failed: %v", err)
+	}
+	if released != 1 {
+		t.Errorf("expected 1 released file, got %d", released)
+	}
+
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process after set-date failed: %v", err)
+	}
+
+	tournament, _ := p.store.GetTournamentByMeleeID(1)
+	if tournament == nil || tournament.Date.Format("2006-01-02") != "2024-08-31" {
+		t.Fatalf("expected tournament with date 2024-08-31, got %+v", tournament)
+	}
+	history, _ := p.store.GetPlayerMatchHistory("Alice")
+	if len(history) != 1 || history[0].PlayerELOAfter <= 1500 {
+		t.Errorf("expected Alice's win to be rated, got %+v", history)
+	}
+	if _, err := os.Stat(filepath.Join(p.config.Paths.ProcessedDir, "Matches-tournament-1.json")); err != nil {
+		t.Errorf("expected file in processed dir: %v", err)
+	}
+}
+
+func TestManifestAndSidecarMetadata(t *testing.T) {
+	p := newTestProcessor(t, nil)
+	p.config.Processing.NonInteractive = true
+	writeTestFile(t, p.config.Paths.Manifest, `tournaments:
+  1:
+    date: 2024-08-31
+    name: Weekly
+    tier: local
+  2:
+    date: 2024-09-07
+`)
+	writePending(t, p, "Matches-tournament-1.json", v2Export(
+		testMatch{Round: 1, Player1: "Alice", Player1Wins: 2, Player2: "Bob", Player2Wins: 0},
+	))
+	writePending(t, p, "Matches-tournament-2.json", v2Export(
+		testMatch{Round: 1, Player1: "Alice", Player1Wins: 2, Player2: "Bob", Player2Wins: 1},
+	))
+	writePending(t, p, "Matches-tournament-2.meta.yaml", "date: 2024-09-08\nlocation: Berlin\n")
+
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	first, _ := p.store.GetTournamentByMeleeID(1)
+	if first == nil || first.Date.Format("2006-01-02") != "2024-08-31" || first.Name != "Weekly" || first.Tier != "local" {
+		t.Errorf("expected manifest metadata for tournament 1, got %+v", first)
+	}
+	second, _ := p.store.GetTournamentByMeleeID(2)
+	if second == nil || second.Date.Format("2006-01-02") != "2024-09-08" || second.Location != "Berlin" {
+		t.Errorf("expected sidecar to win for tournament 2, got %+v", second)
+	}
+
+	for _, name := range []string{"Matches-tournament-2.json", "Matches-tournament-2.meta.yaml"} {
+		if _, err := os.Stat(filepath.Join(p.config.Paths.ProcessedDir, name)); err != nil {
+			t.Errorf("expected %s in processed dir: %v", name, err)
+		}
+	}
+	failed, _ := os.ReadDir(p.config.Paths.FailedDir)
+	if len(failed) != 0 {
+		t.Errorf("expected no failed files, got %d", len(failed))
+	}
+}
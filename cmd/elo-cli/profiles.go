@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/melee-elo-ranking/internal/config"
+	"github.com/melee-elo-ranking/internal/elo"
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+// newCalculator returns a calculator using the given rating parameters.
+// Unset parameters keep the calculator defaults.
+func newCalculator(cfg config.ELOConfig) *elo.Calculator {
+	calc := elo.New(cfg.InitialRating)
+	if cfg.ProvisionalKFactor > 0 && cfg.EstablishedKFactor > 0 {
+		calc.SetKFactors(cfg.ProvisionalKFactor, cfg.EstablishedKFactor)
+	}
+	if cfg.ProvisionalMatches > 0 {
+		calc.SetDynamicKThreshold(cfg.ProvisionalMatches)
+	}
+	return calc
+}
+
+// profileRankings ranks the players of a profile. The main profile uses the
+// stored ratings; other profiles are rated in memory from the matches of the
+// tournaments their filter selects, so the database is left untouched.
+func profileRankings(store *storage.Storage, profile config.ProfileConfig) ([]storage.Ranking, error) {
+	players, err := store.GetAllPlayers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get players: %w", err)
+	}
+	if profile.Name == config.MainProfile {
+		return storage.RankPlayers(players, profile.MinMatches), nil
+	}
+
+	tournaments, err := store.GetAllTournaments()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tournaments: %w", err)
+	}
+	included := make(map[int]bool)
+	for _, t := range tournaments {
+		if profile.Filter.Includes(t.MeleeID, t.Date, t.Tier) {
+			included[t.MeleeID] = true
+		}
+	}
+
+	allMatches, err := store.GetAllMatchesSorted()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get matches: %w", err)
+	}
+	matches := make([]storage.Match, 0, len(allMatches))
+	for _, m := range allMatches {
+		if included[m.TournamentID] {
+			matches = append(matches, m)
+		}
+	}
+
+	calc := newCalculator(profile.ELO)
+	for i := range players {
+		players[i].CurrentELO = calc.GetInitialRating()
+		players[i].MatchesPlayed = 0
+		players[i].Wins = 0
+		players[i].Losses = 0
+	}
+	update := replayMatches(calc, players, matches)
+	return storage.RankPlayers(update.Players, profile.MinMatches), nil
+}
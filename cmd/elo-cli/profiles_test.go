@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/melee-elo-ranking/internal/config"
+)
+
+func TestProfileRankingsFilterTournaments(t *testing.T) {
+	p := newTestProcessor(t, map[int]string{1: "2024-08-31", 2: "2024-09-07"})
+	writePending(t, p, "Matches-tournament-1.json", v2Export(
+		testMatch{Round: 1, Player1: "Alice", Player1Wins: 2, Player2: "Bob", Player2Wins: 0},
+	))
+	writePending(t, p, "Matches-tournament-2.json", v2Export(
+		testMatch{Round: 1, Player1: "Bob", Player1Wins: 2, Player2: "Carol", Player2Wins: 0},
+	))
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if err := p.store.UpdateTournamentMetadata(2, "Weekly", "weekly", "", ""); err != nil {
+		t.Fatalf("failed to set tier: %v", err)
+	}
+
+	main, err := profileRankings(p.store, config.ProfileConfig{Name: config.MainProfile, MinMatches: 1})
+	if err != nil {
+		t.Fatalf("main rankings failed: %v", err)
+	}
+	if len(main) != 3 {
+		t.Fatalf("expected 3 ranked players in main profile, got %d", len(main))
+	}
+
+	weeklies, err := profileRankings(p.store, config.ProfileConfig{
+		Name:       "weeklies",
+		MinMatches: 1,
+		ELO:        config.ELOConfig{InitialRating: 1000},
+		Filter:     config.FilterConfig{Tiers: []string{"weekly"}},
+	})
+	if err != nil {
+		t.Fatalf("weeklies rankings failed: %v", err)
+	}
+	if len(weeklies) != 2 || weeklies[0].DisplayName != "Bob" || weeklies[1].DisplayName != "Carol" {
+		t.Fatalf("expected only Bob and Carol from the weekly, got %+v", weeklies)
+	}
+	if weeklies[0].CurrentELO != 1020 || weeklies[0].MatchesPlayed != 1 {
+		t.Errorf("expected Bob rated from 1000 on one match, got %+v", weeklies[0])
+	}
+
+	// The profile is rated in memory only
+	bob, err := findPlayer(p.store, "Bob")
+	if err != nil {
+		t.Fatalf("findPlayer failed: %v", err)
+	}
+	if bob.MatchesPlayed != 2 {
+		t.Errorf("expected stored ratings to be untouched, got %+v", bob)
+	}
+}
+
+func TestRankingsAsOf(t *testing.T) {
+	p := newTestProcessor(t, map[int]string{1: "2024-08-31", 2: "2024-09-07"})
+	writePending(t, p, "Matches-tournament-1.json", v2Export(
+		testMatch{Round: 1, Player1: "Alice", Player1Wins: 2, Player2: "Bob", Player2Wins: 0},
+	))
+	writePending(t, p, "Matches-tournament-2.json", v2Export(
+		testMatch{Round: 1, Player1: "Bob", Player1Wins: 2, Player2: "Carol", Player2Wins: 0},
+	))
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	main := config.ProfileConfig{Name: config.MainProfile, MinMatches: 1}
+
+	before, err := rankingsAsOf(p.store, main, "2024-09-06")
+	if err != nil {
+		t.Fatalf("rankingsAsOf failed: %v", err)
+	}
+	if len(before) != 2 || before[0].DisplayName != "Alice" || before[1].DisplayName != "Bob" || before[1].MatchesPlayed != 1 {
+		t.Errorf("expected only the first tournament, got %+v", before)
+	}
+
+	// As of the last tournament the history agrees with the stored ratings
+	after, err := rankingsAsOf(p.store, main, "2024-09-07")
+	if err != nil {
+		t.Fatalf("rankingsAsOf failed: %v", err)
+	}
+	current, _ := profileRankings(p.store, main)
+	if len(after) != len(current) {
+		t.Fatalf("expected %d players, got %d", len(current), len(after))
+	}
+	for i := range current {
+		if after[i].DisplayName != current[i].DisplayName || after[i].CurrentELO != current[i].CurrentELO || after[i].Wins != current[i].Wins {
+			t.Errorf("rank %d: expected %+v, got %+v", i+1, current[i], after[i])
+		}
+	}
+
+	// Other profiles are replayed up to the date
+	weeklies, err := rankingsAsOf(p.store, config.ProfileConfig{Name: "weeklies", MinMatches: 1}, "2024-09-01")
+	if err != nil {
+		t.Fatalf("rankingsAsOf failed: %v", err)
+	}
+	if len(weeklies) != 2 || weeklies[0].DisplayName != "Alice" {
+		t.Errorf("expected the profile to stop at the date, got %+v", weeklies)
+	}
+
+	if _, err := rankingsAsOf(p.store, main, "31/12/2024"); err == nil {
+		t.Error("expected an invalid date to be rejected")
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/melee-elo-ranking/internal/elo"
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+// rebuild brings player ratings up to date. Tournaments that have not been
+// rated yet are applied on top of the current ratings when they all come
+// after the last rated tournament; otherwise every match is replayed.
+func (p *Processor) rebuild() error {
+	if p.needsFullRebuild {
+		fmt.Println("Stored matches changed, falling back to full rebuild")
+		return p.fullRebuild()
+	}
+
+	canIncrement, err := p.canRateIncrementally()
+	if err != nil {
+		return err
+	}
+	if !canIncrement {
+		return p.fullRebuild()
+	}
+	return p.incrementalRebuild()
+}
+
+// canRateIncrementally reports whether every unrated tournament comes after
+// the last rated one in the order matches are replayed.
+func (p *Processor) canRateIncrementally() (bool, error) {
+	last, err := p.store.GetLastRatedTournament()
+	if err != nil {
+		return false, fmt.Errorf("failed to get last rated tournament: %w", err)
+	}
+	if last == nil {
+		return false, nil
+	}
+
+	// Databases rated before the history was kept need it filled in once
+	hasHistory, err := p.store.HasRatingHistory()
+	if err != nil {
+		return false, fmt.Errorf("failed to check rating history: %w", err)
+	}
+	if !hasHistory {
+		fmt.Println("Rating history is missing, falling back to full rebuild")
+		return false, nil
+	}
+
+	unrated, err := p.store.GetUnratedTournaments()
+	if err != nil {
+		return false, fmt.Errorf("failed to get unrated tournaments: %w", err)
+	}
+
+	for _, t := range unrated {
+		if !tournamentAfter(t, *last) {
+			fmt.Printf("Tournament %d is older than last rated tournament %d, falling back to full rebuild\n", t.MeleeID, last.MeleeID)
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// tournamentAfter reports whether a is replayed after b. Tournaments without
+// a date are ordered at storage.UndatedSortDate like in the sorted queries,
+// ties are broken by melee ID.
+func tournamentAfter(a, b storage.Tournament) bool {
+	if dateA, dateB := a.SortDate(), b.SortDate(); !dateA.Equal(dateB) {
+		return dateA.After(dateB)
+	}
+	return a.MeleeID > b.MeleeID
+}
+
+func (p *Processor) incrementalRebuild() error {
+	unrated, err := p.store.GetUnratedTournaments()
+	if err != nil {
+		return fmt.Errorf("failed to get unrated tournaments: %w", err)
+	}
+	if len(unrated) == 0 {
+		fmt.Println("Ratings are up to date")
+		return nil
+	}
+
+	fmt.Println("Performing incremental ELO update...")
+
+	players, err := p.store.GetAllPlayers()
+	if err != nil {
+		return fmt.Errorf("failed to get players: %w", err)
+	}
+	matches, err := p.store.GetUnratedMatchesSorted()
+	if err != nil {
+		return fmt.Errorf("failed to get matches: %w", err)
+	}
+
+	fmt.Printf("Processing %d matches from %d new tournaments...\n", len(matches), len(unrated))
+
+	update := p.replay(players, matches)
+	for _, t := range unrated {
+		update.RatedTournaments = append(update.RatedTournaments, t.MeleeID)
+	}
+	if err := p.store.ApplyRatingUpdate(update); err != nil {
+		return fmt.Errorf("failed to save ratings: %w", err)
+	}
+	if err := p.saveSnapshot(); err != nil {
+		return err
+	}
+
+	fmt.Println("Incremental update complete")
+	return nil
+}
+
+func (p *Processor) fullRebuild() error {
+	fmt.Println("Performing full ELO rebuild...")
+
+	players, err := p.store.GetAllPlayers()
+	if err != nil {
+		return fmt.Errorf("failed to get players: %w", err)
+	}
+	for i := range players {
+		players[i].CurrentELO = p.calculator.GetInitialRating()
+		players[i].MatchesPlayed = 0
+		players[i].Wins = 0
+		players[i].Losses = 0
+	}
+
+	allMatches, err := p.store.GetAllMatchesSorted()
+	if err != nil {
+		return fmt.Errorf("failed to get matches: %w", err)
+	}
+
+	fmt.Printf("Processing %d matches in chronological order...\n", len(allMatches))
+
+	update := p.replay(players, allMatches)
+	update.RateAll = true
+	if err := p.store.ApplyRatingUpdate(update); err != nil {
+		return fmt.Errorf("failed to save ratings: %w", err)
+	}
+	p.needsFullRebuild = false
+	if err := p.saveSnapshot(); err != nil {
+		return err
+	}
+
+	fmt.Println("Full rebuild complete")
+	return nil
+}
+
+// saveSnapshot records the current rankings under the date of the last rated
+// tournament, so the site can show how they moved since the previous one.
+func (p *Processor) saveSnapshot() error {
+	last, err := p.store.GetLastRatedTournament()
+	if err != nil {
+		return fmt.Errorf("failed to get last rated tournament: %w", err)
+	}
+	if last == nil || last.Date.IsZero() {
+		return nil
+	}
+	players, err := p.store.GetAllPlayers()
+	if err != nil {
+		return fmt.Errorf("failed to get players: %w", err)
+	}
+	rankings := storage.RankPlayers(players, p.config.Output.MinMatches)
+	if err := p.store.SaveRankingSnapshot(last.Date, rankings); err != nil {
+		return fmt.Errorf("failed to save ranking snapshot: %w", err)
+	}
+	return nil
+}
+
+func (p *Processor) replay(players []storage.Player, matches []storage.Match) storage.RatingUpdate {
+	return replayMatches(p.calculator, players, matches)
+}
+
+// replayMatches applies matches, in order, to the given player states in
+// memory and returns the resulting player totals along with the ratings to
+// record on each match and the totals after each tournament. Every player
+// passed in is included in the update.
+func replayMatches(calculator *elo.Calculator, players []storage.Player, matches []storage.Match) storage.RatingUpdate {
+	byID := make(map[int64]*storage.Player, len(players))
+	for i := range players {
+		byID[players[i].ID] = &players[i]
+	}
+
+	type historyKey struct {
+		playerID     int64
+		tournamentID int
+	}
+	var history []storage.RatingHistoryEntry
+	historyIndex := make(map[historyKey]int)
+	recordHistory := func(player *storage.Player, match storage.Match) {
+		key := historyKey{player.ID, match.TournamentID}
+		i, ok := historyIndex[key]
+		if !ok {
+			i = len(history)
+			historyIndex[key] = i
+			history = append(history, storage.RatingHistoryEntry{
+				PlayerID:     player.ID,
+				TournamentID: match.TournamentID,
+				Date:         match.DatePlayed,
+			})
+		}
+		history[i].ELO = player.CurrentELO
+		history[i].MatchesPlayed = player.MatchesPlayed
+		history[i].Wins = player.Wins
+		history[i].Losses = player.Losses
+	}
+
+	rated := make([]storage.Match, 0, len(matches))
+	for _, match := range matches {
+		player1, ok1 := byID[match.Player1ID]
+		player2, ok2 := byID[match.Player2ID]
+		if !ok1 || !ok2 {
+			fmt.Printf("Warning: failed to process match %s: unknown player\n", match.ID)
+			continue
+		}
+
+		var winnerID *int64
+		if match.Player1Wins > match.Player2Wins {
+			winnerID = &match.Player1ID
+		} else if match.Player2Wins > match.Player1Wins {
+			winnerID = &match.Player2ID
+		}
+
+		newELO1, newELO2 := calculator.Calculate(
+			player1.CurrentELO,
+			player2.CurrentELO,
+			winnerID,
+			&match.Player1ID,
+			&match.Player2ID,
+			player1.MatchesPlayed,
+			player2.MatchesPlayed,
+		)
+
+		match.Player1ELOBefore = player1.CurrentELO
+		match.Player2ELOBefore = player2.CurrentELO
+		match.Player1ELOAfter = newELO1
+		match.Player2ELOAfter = newELO2
+		rated = append(rated, match)
+
+		applyResult(player1, newELO1, match.Player1Wins > match.Player2Wins)
+		applyResult(player2, newELO2, match.Player2Wins > match.Player1Wins)
+		recordHistory(player1, match)
+		recordHistory(player2, match)
+	}
+
+	return storage.RatingUpdate{
+		Players: players,
+		Matches: rated,
+		History: history,
+	}
+}
+
+// applyResult mirrors storage.UpdatePlayerELO on an in-memory player: a draw
+// counts as a loss for both sides.
+func applyResult(player *storage.Player, newELO int, won bool) {
+	player.CurrentELO = newELO
+	player.MatchesPlayed++
+	if won {
+		player.Wins++
+	} else {
+		player.Losses++
+	}
+}
+
+// Rebuild replays every stored match from scratch.
+func (p *Processor) Rebuild() error {
+	return p.fullRebuild()
+}
@@ -0,0 +1,458 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/melee-elo-ranking/internal/config"
+	"github.com/melee-elo-ranking/internal/elo"
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+// ratingState captures everything a rebuild writes: player totals and the
+// per-match rating history of every player.
+type ratingState struct {
+	Players map[string]storage.Player
+	History map[string][]storage.PlayerMatch
+}
+
+func captureRatingState(t *testing.T, store *storage.Storage) ratingState {
+	t.Helper()
+	players, err := store.GetAllPlayers()
+	if err != nil {
+		t.Fatalf("failed to get players: %v", err)
+	}
+
+	state := ratingState{
+		Players: make(map[string]storage.Player),
+		History: make(map[string][]storage.PlayerMatch),
+	}
+	for _, p := range players {
+		history, err := store.GetPlayerMatchHistory(p.DisplayName)
+		if err != nil {
+			t.Fatalf("failed to get history for %s: %v", p.DisplayName, err)
+		}
+		state.Players[p.DisplayName] = storage.Player{
+			DisplayName:   p.DisplayName,
+			CurrentELO:    p.CurrentELO,
+			MatchesPlayed: p.MatchesPlayed,
+			Wins:          p.Wins,
+			Losses:        p.Losses,
+		}
+		state.History[p.DisplayName] = history
+	}
+	return state
+}
+
+var (
+	weekOne = v2Export(
+		testMatch{Round: 1, Player1: "Alice", Player1Wins: 2, Player2: "Bob", Player2Wins: 0},
+		testMatch{Round: 1, Player1: "Carol", Player1Wins: 2, Player2: "Dave", Player2Wins: 1},
+		testMatch{Round: 2, Player1: "Alice", Player1Wins: 2, Player2: "Carol", Player2Wins: 1},
+		testMatch{Round: 2, Player1: "Bob", Player1Wins: 0, Player2: "Dave", Player2Wins: 2},
+	)
+	weekTwo = v2Export(
+		testMatch{Round: 1, Player1: "Bob", Player1Wins: 2, Player2: "Alice", Player2Wins: 1},
+		testMatch{Round: 1, Player1: "Dave", Player1Wins: 2, Player2: "Erin", Player2Wins: 0},
+		testMatch{Round: 2, Player1: "Bob", Player1Wins: 2, Player2: "Dave", Player2Wins: 0},
+	)
+	weekThree = v2Export(
+		testMatch{Round: 1, Player1: "Erin", Player1Wins: 2, Player2: "Alice", Player2Wins: 0},
+		testMatch{Round: 1, Player1: "Carol", Player1Wins: 1, Player2: "Bob", Player2Wins: 2},
+	)
+	weeklyDates = map[int]string{1: "2024-08-01", 2: "2024-08-08", 3: "2024-08-15"}
+)
+
+// referenceState ingests all three weeks in one run, which rates them with a
+// full rebuild.
+func referenceState(t *testing.T) ratingState {
+	t.Helper()
+	p := newTestProcessor(t, weeklyDates)
+	writePending(t, p, "Matches-tournament-1.json", weekOne)
+	writePending(t, p, "Matches-tournament-2.json", weekTwo)
+	writePending(t, p, "Matches-tournament-3.json", weekThree)
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	return captureRatingState(t, p.store)
+}
+
+func TestIncrementalMatchesFullRebuild(t *testing.T) {
+	want := referenceState(t)
+
+	p := newTestProcessor(t, weeklyDates)
+	writePending(t, p, "Matches-tournament-1.json", weekOne)
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process week one failed: %v", err)
+	}
+
+	writePending(t, p, "Matches-tournament-2.json", weekTwo)
+	writePending(t, p, "Matches-tournament-3.json", weekThree)
+	if err := p.Ingest(); err != nil {
+		t.Fatalf("Ingest weeks two and three failed: %v", err)
+	}
+	canIncrement, err := p.canRateIncrementally()
+	if err != nil {
+		t.Fatalf("canRateIncrementally failed: %v", err)
+	}
+	if !canIncrement || p.needsFullRebuild {
+		t.Fatal("expected weeks two and three to be rated incrementally")
+	}
+	if err := p.rebuild(); err != nil {
+		t.Fatalf("rebuild failed: %v", err)
+	}
+
+	got := captureRatingState(t, p.store)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("incremental ratings differ from full rebuild\ngot:  %+v\nwant: %+v", got.Players, want.Players)
+	}
+}
+
+func TestRebuildSavesRankingSnapshots(t *testing.T) {
+	p := newTestProcessor(t, weeklyDates)
+	writePending(t, p, "Matches-tournament-1.json", weekOne)
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process week one failed: %v", err)
+	}
+	writePending(t, p, "Matches-tournament-2.json", weekTwo)
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process week two failed: %v", err)
+	}
+
+	snapshots, err := p.store.GetRankingSnapshots()
+	if err != nil {
+		t.Fatalf("GetRankingSnapshots failed: %v", err)
+	}
+	dates := make(map[string]int)
+	for _, e := range snapshots {
+		dates[e.Date.Format("2006-01-02")]++
+	}
+	if len(dates) != 2 || dates["2024-08-01"] != 4 || dates["2024-08-08"] == 0 {
+		t.Errorf("expected a snapshot after each week, got %v", dates)
+	}
+
+	// A full rebuild replaces the latest snapshot rather than adding one
+	if err := p.Rebuild(); err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+	again, _ := p.store.GetRankingSnapshots()
+	if len(again) != len(snapshots) {
+		t.Errorf("expected %d snapshot entries after rebuild, got %d", len(snapshots), len(again))
+	}
+}
+
+func TestOutOfOrderTournamentFallsBackToFullRebuild(t *testing.T) {
+	want := referenceState(t)
+
+	p := newTestProcessor(t, weeklyDates)
+	writePending(t, p, "Matches-tournament-1.json", weekOne)
+	writePending(t, p, "Matches-tournament-3.json", weekThree)
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process weeks one and three failed: %v", err)
+	}
+
+	// Week two arrives late and has to be slotted in before week three.
+	writePending(t, p, "Matches-tournament-2.json", weekTwo)
+	if err := p.Ingest(); err != nil {
+		t.Fatalf("Ingest week two failed: %v", err)
+	}
+	canIncrement, err := p.canRateIncrementally()
+	if err != nil {
+		t.Fatalf("canRateIncrementally failed: %v", err)
+	}
+	if canIncrement {
+		t.Fatal("expected out-of-order tournament to require a full rebuild")
+	}
+	if err := p.rebuild(); err != nil {
+		t.Fatalf("rebuild failed: %v", err)
+	}
+
+	got := captureRatingState(t, p.store)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ratings after out-of-order ingest differ from full rebuild\ngot:  %+v\nwant: %+v", got.Players, want.Players)
+	}
+}
+
+func TestMovedTournamentRebuildsAfterRestart(t *testing.T) {
+	moved := map[int]string{1: "2024-08-20", 2: "2024-08-08", 3: "2024-08-15"}
+	reference := newTestProcessor(t, moved)
+	writePending(t, reference, "Matches-tournament-1.json", weekOne)
+	writePending(t, reference, "Matches-tournament-2.json", weekTwo)
+	writePending(t, reference, "Matches-tournament-3.json", weekThree)
+	if err := reference.Process(); err != nil {
+		t.Fatalf("Process reference failed: %v", err)
+	}
+	want := captureRatingState(t, reference.store)
+
+	p := newTestProcessor(t, weeklyDates)
+	writePending(t, p, "Matches-tournament-1.json", weekOne)
+	writePending(t, p, "Matches-tournament-2.json", weekTwo)
+	writePending(t, p, "Matches-tournament-3.json", weekThree)
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if _, err := p.SetTournamentDate(1, time.Date(2024, 8, 20, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("SetTournamentDate failed: %v", err)
+	}
+
+	// A later run starts from a fresh processor and must still replay
+	restarted := NewProcessor(p.store, p.calculator, p.parser, nil, nil, p.config)
+	canIncrement, err := restarted.canRateIncrementally()
+	if err != nil {
+		t.Fatalf("canRateIncrementally failed: %v", err)
+	}
+	if canIncrement {
+		t.Fatal("expected a moved tournament to require a full rebuild")
+	}
+	if err := restarted.rebuild(); err != nil {
+		t.Fatalf("rebuild failed: %v", err)
+	}
+
+	got := captureRatingState(t, restarted.store)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ratings after moving a tournament differ from full rebuild\ngot:  %+v\nwant: %+v", got.Players, want.Players)
+	}
+}
+
+func TestSameDayDateKeepsTournamentsRated(t *testing.T) {
+	// Tournament 1 was stored with the start time scraped from melee.gg
+	p := newTestProcessor(t, map[int]string{2: "2024-08-08", 3: "2024-08-15"})
+	scraped := time.Date(2024, 8, 1, 7, 0, 0, 0, time.UTC)
+	if _, err := p.store.GetOrCreateTournament(1, scraped); err != nil {
+		t.Fatalf("GetOrCreateTournament failed: %v", err)
+	}
+	writePending(t, p, "Matches-tournament-1.json", weekOne)
+	writePending(t, p, "Matches-tournament-2.json", weekTwo)
+	writePending(t, p, "Matches-tournament-3.json", weekThree)
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	existing, err := p.store.GetTournamentByMeleeID(1)
+	if err != nil {
+		t.Fatalf("GetTournamentByMeleeID failed: %v", err)
+	}
+	// The manifest names the same day without a time: nothing moves
+	p.updateStoredDate(*existing, time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC))
+
+	unrated, err := p.store.GetUnratedTournaments()
+	if err != nil {
+		t.Fatalf("GetUnratedTournaments failed: %v", err)
+	}
+	if len(unrated) != 0 {
+		t.Errorf("expected all tournaments to stay rated, %d were unrated", len(unrated))
+	}
+}
+
+func TestCanRateIncrementally(t *testing.T) {
+	p := newTestProcessor(t, weeklyDates)
+
+	// Nothing rated yet: incremental updates have no base to build on.
+	ok, err := p.canRateIncrementally()
+	if err != nil {
+		t.Fatalf("canRateIncrementally failed: %v", err)
+	}
+	if ok {
+		t.Error("expected full rebuild for an empty database")
+	}
+
+	writePending(t, p, "Matches-tournament-2.json", weekTwo)
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	writePending(t, p, "Matches-tournament-3.json", weekThree)
+	if err := p.Ingest(); err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+	ok, err = p.canRateIncrementally()
+	if err != nil {
+		t.Fatalf("canRateIncrementally failed: %v", err)
+	}
+	if !ok {
+		t.Error("expected incremental update for a later tournament")
+	}
+}
+
+func TestTournamentAfter(t *testing.T) {
+	undated := storage.Tournament{MeleeID: 1}
+	before1970 := storage.Tournament{MeleeID: 2, Date: time.Date(1969, 12, 31, 0, 0, 0, 0, time.UTC)}
+	epoch := storage.Tournament{MeleeID: 3, Date: storage.UndatedSortDate}
+	later := storage.Tournament{MeleeID: 4, Date: time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name string
+		a, b storage.Tournament
+		want bool
+	}{
+		// The sorted queries order an undated tournament at 1970-01-01, so
+		// it comes after anything dated earlier.
+		{"undated after 1969", undated, before1970, true},
+		{"1969 before undated", before1970, undated, false},
+		{"undated ties with epoch by melee ID", epoch, undated, true},
+		{"dated after undated", later, undated, true},
+		{"undated before dated", undated, later, false},
+	}
+	for _, tt := range tests {
+		if got := tournamentAfter(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: tournamentAfter = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// perMatchRebuild is the rebuild as it was done before the in-memory engine:
+// two player lookups and three UPDATEs per match, outside a transaction.
+// It serves as the reference for the in-memory replay.
+func perMatchRebuild(store *storage.Storage, calculator *elo.Calculator) error {
+	if err := store.ResetAllPlayersELO(); err != nil {
+		return err
+	}
+	matches, err := store.GetAllMatchesSorted()
+	if err != nil {
+		return err
+	}
+
+	for _, match := range matches {
+		player1, err := store.GetPlayerByID(match.Player1ID)
+		if err != nil {
+			return err
+		}
+		player2, err := store.GetPlayerByID(match.Player2ID)
+		if err != nil {
+			return err
+		}
+
+		var winnerID *int64
+		if match.Player1Wins > match.Player2Wins {
+			winnerID = &match.Player1ID
+		} else if match.Player2Wins > match.Player1Wins {
+			winnerID = &match.Player2ID
+		}
+
+		newELO1, newELO2 := calculator.Calculate(player1.CurrentELO, player2.CurrentELO, winnerID,
+			&match.Player1ID, &match.Player2ID, player1.MatchesPlayed, player2.MatchesPlayed)
+
+		if err := store.UpdatePlayerELO(match.Player1ID, newELO1, match.Player1Wins > match.Player2Wins); err != nil {
+			return err
+		}
+		if err := store.UpdatePlayerELO(match.Player2ID, newELO2, match.Player2Wins > match.Player1Wins); err != nil {
+			return err
+		}
+		if err := store.UpdateMatchELO(match.ID, player1.CurrentELO, player2.CurrentELO, newELO1, newELO2); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestInMemoryRebuildMatchesPerMatchRebuild(t *testing.T) {
+	p := newTestProcessor(t, nil)
+	seedSyntheticMatches(t, p.store, 40, 30, 20)
+
+	if err := perMatchRebuild(p.store, p.calculator); err != nil {
+		t.Fatalf("perMatchRebuild failed: %v", err)
+	}
+	want := captureRatingState(t, p.store)
+
+	if err := p.fullRebuild(); err != nil {
+		t.Fatalf("fullRebuild failed: %v", err)
+	}
+	got := captureRatingState(t, p.store)
+
+	if !reflect.DeepEqual(got, want) {
+		t.Error("in-memory rebuild differs from per-match rebuild")
+	}
+}
+
+// seedSyntheticMatches fills the store with randomly paired matches spread
+// over weekly tournaments. The seed is fixed so runs are comparable.
+func seedSyntheticMatches(tb testing.TB, store *storage.Storage, players, tournaments, matchesPerTournament int) {
+	tb.Helper()
+	rng := rand.New(rand.NewSource(1))
+
+	ids := make([]int64, players)
+	for i := range ids {
+		player, err := store.GetOrCreatePlayer(int64(i+1), fmt.Sprintf("Player%04d", i+1), "")
+		if err != nil {
+			tb.Fatalf("failed to create player: %v", err)
+		}
+		ids[i] = player.ID
+	}
+
+	start := time.Date(2020, 1, 4, 0, 0, 0, 0, time.UTC)
+	for tIdx := 0; tIdx < tournaments; tIdx++ {
+		meleeID := tIdx + 1
+		date := start.AddDate(0, 0, 7*tIdx)
+		if _, err := store.GetOrCreateTournament(meleeID, date); err != nil {
+			tb.Fatalf("failed to create tournament: %v", err)
+		}
+
+		matches := make([]storage.Match, matchesPerTournament)
+		for m := range matches {
+			a := rng.Intn(players)
+			b := (a + 1 + rng.Intn(players-1)) % players
+			wins1, wins2 := 2, rng.Intn(2)
+			if rng.Intn(2) == 0 {
+				wins1, wins2 = wins2, wins1
+			}
+			matches[m] = storage.Match{
+				ID:           fmt.Sprintf("%d-%d", meleeID, m),
+				TournamentID: meleeID,
+				Round:        m/(players/2) + 1,
+				Player1ID:    ids[a],
+				Player2ID:    ids[b],
+				Player1Wins:  wins1,
+				Player2Wins:  wins2,
+				DatePlayed:   date,
+			}
+		}
+		if err := store.SaveMatches(matches); err != nil {
+			tb.Fatalf("failed to save matches: %v", err)
+		}
+	}
+}
+
+// newBenchmarkStore returns a store seeded with 100k matches: 500 players
+// over 1000 tournaments of 100 matches each.
+func newBenchmarkStore(b *testing.B) (*storage.Storage, *elo.Calculator) {
+	b.Helper()
+	store, err := storage.New(filepath.Join(b.TempDir(), "bench.db"))
+	if err != nil {
+		b.Fatalf("failed to open storage: %v", err)
+	}
+	b.Cleanup(func() { store.Close() })
+	seedSyntheticMatches(b, store, 500, 1000, 100)
+	return store, elo.New(1500)
+}
+
+// BenchmarkFullRebuild measures the in-memory rebuild on 100k matches.
+//
+//	go test ./cmd/elo-cli -run '^$' -bench FullRebuild -benchtime 1x
+func BenchmarkFullRebuild(b *testing.B) {
+	store, calculator := newBenchmarkStore(b)
+	p := &Processor{store: store, calculator: calculator, config: &config.Config{}}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := p.fullRebuild(); err != nil {
+			b.Fatalf("fullRebuild failed: %v", err)
+		}
+	}
+}
+
+// BenchmarkPerMatchRebuild measures the previous per-match rebuild on the
+// same dataset, for comparison with BenchmarkFullRebuild.
+func BenchmarkPerMatchRebuild(b *testing.B) {
+	store, calculator := newBenchmarkStore(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := perMatchRebuild(store, calculator); err != nil {
+			b.Fatalf("perMatchRebuild failed: %v", err)
+		}
+	}
+}
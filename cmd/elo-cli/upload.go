@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/melee-elo-ranking/internal/impact"
+	"github.com/melee-elo-ranking/internal/server"
+)
+
+// IngestUpload implements server.Ingester. The export is written to the
+// pending dir and ingested and rated exactly like a file dropped there by
+// hand, so duplicate detection, diffs against stored matches and the
+// needs-date queue all apply. Uploads are handled one at a time.
+func (p *Processor) IngestUpload(tournamentID int, date string, data []byte) (impact.Report, error) {
+	p.uploadMu.Lock()
+	defer p.uploadMu.Unlock()
+
+	hash := hashBytes(data)
+	duplicate, err := p.store.GetTournamentByContentHash(hash)
+	if err != nil {
+		return impact.Report{}, fmt.Errorf("failed to look up content hash: %w", err)
+	}
+	if duplicate != nil {
+		return impact.Report{}, fmt.Errorf("tournament %d: %w", duplicate.MeleeID, server.ErrDuplicate)
+	}
+
+	before, err := impact.Take(p.store, p.config.Output.MinMatches)
+	if err != nil {
+		return impact.Report{}, err
+	}
+
+	// The prefix keeps uploads for the same tournament apart while still
+	// matching extractTournamentID.
+	filename := fmt.Sprintf("upload-%d-Matches-tournament-%d.json", time.Now().UnixNano(), tournamentID)
+	if err := os.WriteFile(filepath.Join(p.config.Paths.PendingDir, filename), data, 0644); err != nil {
+		return impact.Report{}, fmt.Errorf("failed to store upload: %w", err)
+	}
+
+	if date != "" {
+		if p.tournamentDates == nil {
+			p.tournamentDates = make(map[int]string)
+		}
+		p.tournamentDates[tournamentID] = date
+		defer delete(p.tournamentDates, tournamentID)
+	}
+
+	if err := p.ingestFiles([]string{filename}); err != nil {
+		return impact.Report{}, err
+	}
+	if err := p.rebuild(); err != nil {
+		return impact.Report{}, err
+	}
+
+	stored, err := p.store.GetTournamentByContentHash(hash)
+	if err != nil {
+		return impact.Report{}, fmt.Errorf("failed to look up content hash: %w", err)
+	}
+	if stored == nil {
+		if _, err := os.Stat(filepath.Join(p.config.Paths.NeedsDateDir, filename)); err == nil {
+			return impact.Report{}, fmt.Errorf("tournament %d: %w", tournamentID, server.ErrNeedsDate)
+		}
+		return impact.Report{}, fmt.Errorf("upload %s for tournament %d was not ingested", filename, tournamentID)
+	}
+
+	after, err := impact.Take(p.store, p.config.Output.MinMatches)
+	if err != nil {
+		return impact.Report{}, err
+	}
+	return impact.Compare(before, after), nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/melee-elo-ranking/internal/server"
+)
+
+func TestIngestUpload(t *testing.T) {
+	p := newTestProcessor(t, nil)
+	p.config.Processing.NonInteractive = true
+	export := v2Export(
+		testMatch{Round: 1, Player1: "Alice", Player1Wins: 2, Player2: "Bob", Player2Wins: 0},
+	)
+
+	report, err := p.IngestUpload(1, "2024-08-31", []byte(export))
+	if err != nil {
+		t.Fatalf("IngestUpload failed: %v", err)
+	}
+	if len(report.Changes) != 2 || !report.Changes[0].New {
+		t.Errorf("expected two new players in the report, got %+v", report.Changes)
+	}
+	tournament, _ := p.store.GetTournamentByMeleeID(1)
+	if tournament == nil || tournament.Date.Format("2006-01-02") != "2024-08-31" || !tournament.Rated {
+		t.Fatalf("expected rated tournament dated 2024-08-31, got %+v", tournament)
+	}
+	if _, ok := p.tournamentDates[1]; ok {
+		t.Error("expected upload date not to stick to later uploads")
+	}
+
+	if _, err := p.IngestUpload(1, "", []byte(export)); !errors.Is(err, server.ErrDuplicate) {
+		t.Errorf("expected ErrDuplicate for identical upload, got %v", err)
+	}
+
+	_, err = p.IngestUpload(2, "", []byte(v2Export(
+		testMatch{Round: 1, Player1: "Bob", Player1Wins: 2, Player2: "Alice", Player2Wins: 1},
+	)))
+	if !errors.Is(err, server.ErrNeedsDate) {
+		t.Errorf("expected ErrNeedsDate without a date, got %v", err)
+	}
+}
@@ -2,4 +2,8 @@ module github.com/melee-elo-ranking
 
 go 1.21
 
-require github.com/mattn/go-sqlite3 v1.14.19
+require (
+	github.com/BurntSushi/toml v1.4.0
+	github.com/mattn/go-sqlite3 v1.14.19
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -2,24 +2,50 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	ELO    ELOConfig    `json:"elo"`
-	Paths  PathsConfig  `json:"paths"`
-	Output OutputConfig `json:"output"`
+	ELO        ELOConfig        `json:"elo"`
+	Paths      PathsConfig      `json:"paths"`
+	Output     OutputConfig     `json:"output"`
+	Processing ProcessingConfig `json:"processing"`
+	Server     ServerConfig     `json:"server"`
+	// Profiles are extra rankings rated from a subset of the stored
+	// tournaments. The top-level settings form the main profile.
+	Profiles []ProfileConfig `json:"profiles"`
+
+	// envProblems are the ELO_* overrides Load failed to parse. Validate
+	// reports them along with every other problem.
+	envProblems []string
 }
 
 type ELOConfig struct {
+	// Deprecated: KFactor is not used for rating, set ProvisionalKFactor and
+	// EstablishedKFactor instead. It is still read so older configs load.
 	KFactor       int `json:"k_factor"`
 	InitialRating int `json:"initial_rating"`
+	// ProvisionalKFactor applies until a player has ProvisionalMatches
+	// matches, EstablishedKFactor afterwards.
+	ProvisionalKFactor int `json:"provisional_k_factor"`
+	EstablishedKFactor int `json:"established_k_factor"`
+	ProvisionalMatches int `json:"provisional_matches"`
 }
 
 type PathsConfig struct {
 	PendingDir   string `json:"pending_dir"`
 	ProcessedDir string `json:"processed_dir"`
 	FailedDir    string `json:"failed_dir"`
+	NeedsDateDir string `json:"needs_date_dir"`
+	Manifest     string `json:"manifest"`
 	Database     string `json:"database"`
 	Output       string `json:"output"`
 }
@@ -28,26 +54,147 @@ type OutputConfig struct {
 	Type        string `json:"type"`
 	Title       string `json:"title"`
 	Description string `json:"description"`
+	// MinMatches is the number of matches a player needs to be ranked.
+	MinMatches int `json:"min_matches"`
+	// MatchupMinSets is the number of sets below which a matchup matrix
+	// cell is greyed out as too small a sample.
+	MatchupMinSets int `json:"matchup_min_sets"`
+	// ClubTopMembers is how many of a club's best players its average
+	// rating is taken over. Clubs with fewer ranked players are not ranked.
+	ClubTopMembers int `json:"club_top_members"`
 }
 
+type ProcessingConfig struct {
+	// NonInteractive parks tournaments with unknown dates in NeedsDateDir
+	// instead of prompting for them on stdin.
+	NonInteractive bool `json:"non_interactive"`
+}
+
+type ServerConfig struct {
+	// UploadToken is the shared secret TOs send as a bearer token to submit
+	// tournaments to the HTTP server. Uploads are disabled while it is empty.
+	UploadToken string `json:"upload_token"`
+}
+
+// Load reads the config file at path and applies ELO_* environment overrides
+// on top of it. Files ending in .yaml, .yml or .toml are read as YAML or
+// TOML with the same field names as the JSON format. Call Validate on the
+// result before using it.
 func Load(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
+	data, err = toJSON(path, data)
+	if err != nil {
+		return nil, err
+	}
+
 	var cfg Config
 	if err := json.Unmarshal(data, &cfg); err != nil {
 		return nil, err
 	}
 
-	// Set defaults if not specified
-	if cfg.ELO.KFactor == 0 {
-		cfg.ELO.KFactor = 32
-	}
+	// Set defaults if not specified. Environment overrides come after, so an
+	// explicit zero there is validated instead of replaced.
 	if cfg.ELO.InitialRating == 0 {
 		cfg.ELO.InitialRating = 1500
 	}
+	if cfg.Paths.NeedsDateDir == "" {
+		cfg.Paths.NeedsDateDir = "data/matches-needs-date"
+	}
+	if cfg.Paths.Manifest == "" {
+		cfg.Paths.Manifest = "data/tournaments.yaml"
+	}
+	if cfg.Output.Type == "" {
+		cfg.Output.Type = "file"
+	}
+	if cfg.Output.MinMatches == 0 {
+		cfg.Output.MinMatches = 10
+	}
+	if cfg.Output.MatchupMinSets == 0 {
+		cfg.Output.MatchupMinSets = 3
+	}
+	if cfg.Output.ClubTopMembers == 0 {
+		cfg.Output.ClubTopMembers = 3
+	}
+	cfg.ELO = cfg.ELO.withDefaults(ELOConfig{
+		ProvisionalKFactor: 40,
+		EstablishedKFactor: 20,
+		ProvisionalMatches: 30,
+	})
+
+	var envErr *ValidationError
+	if err := ApplyEnv(&cfg, os.LookupEnv); errors.As(err, &envErr) {
+		cfg.envProblems = envErr.Problems
+	} else if err != nil {
+		return nil, err
+	}
 
 	return &cfg, nil
 }
+
+// toJSON converts a YAML or TOML config to JSON so that all formats share the
+// json struct tags. JSON configs are returned unchanged.
+func toJSON(path string, data []byte) ([]byte, error) {
+	var generic map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &generic); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	case ".toml":
+		if err := toml.Unmarshal(data, &generic); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	default:
+		return data, nil
+	}
+	if generic == nil {
+		generic = map[string]interface{}{}
+	}
+	return json.Marshal(normalizeDates(generic))
+}
+
+// normalizeDates turns the timestamps YAML and TOML produce for unquoted
+// dates back into YYYY-MM-DD strings, as they would be written in JSON.
+func normalizeDates(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = normalizeDates(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = normalizeDates(value)
+		}
+	case []map[string]interface{}:
+		for i, value := range v {
+			v[i] = normalizeDates(value).(map[string]interface{})
+		}
+	case time.Time:
+		if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 && v.Nanosecond() == 0 {
+			return v.Format("2006-01-02")
+		}
+		return v.Format(time.RFC3339)
+	}
+	return v
+}
+
+// withDefaults fills the unset fields of e from defaults.
+func (e ELOConfig) withDefaults(defaults ELOConfig) ELOConfig {
+	if e.InitialRating == 0 {
+		e.InitialRating = defaults.InitialRating
+	}
+	if e.ProvisionalKFactor == 0 {
+		e.ProvisionalKFactor = defaults.ProvisionalKFactor
+	}
+	if e.EstablishedKFactor == 0 {
+		e.EstablishedKFactor = defaults.EstablishedKFactor
+	}
+	if e.ProvisionalMatches == 0 {
+		e.ProvisionalMatches = defaults.ProvisionalMatches
+	}
+	return e
+}
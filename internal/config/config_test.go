@@ -1,9 +1,12 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -27,6 +30,9 @@ func TestLoadConfig(t *testing.T) {
 			"type": "file",
 			"title": "Test Rankings",
 			"description": "Test description"
+		},
+		"processing": {
+			"non_interactive": true
 		}
 	}`
 
@@ -49,6 +55,9 @@ func TestLoadConfig(t *testing.T) {
 	if cfg.Output.Title != "Test Rankings" {
 		t.Errorf("expected title 'Test Rankings', got %s", cfg.Output.Title)
 	}
+	if !cfg.Processing.NonInteractive {
+		t.Error("expected non_interactive to be true")
+	}
 }
 
 func TestLoadConfigDefaults(t *testing.T) {
@@ -75,12 +84,21 @@ func TestLoadConfigDefaults(t *testing.T) {
 	}
 
 	// Check default values
-	if cfg.ELO.KFactor != 32 {
-		t.Errorf("expected default k_factor 32, got %d", cfg.ELO.KFactor)
+	if cfg.ELO.ProvisionalKFactor != 40 || cfg.ELO.EstablishedKFactor != 20 {
+		t.Errorf("expected default k-factors 40 and 20, got %d and %d", cfg.ELO.ProvisionalKFactor, cfg.ELO.EstablishedKFactor)
 	}
 	if cfg.ELO.InitialRating != 1500 {
 		t.Errorf("expected default initial_rating 1500, got %d", cfg.ELO.InitialRating)
 	}
+	if cfg.Paths.NeedsDateDir != "data/matches-needs-date" {
+		t.Errorf("expected default needs_date_dir, got %s", cfg.Paths.NeedsDateDir)
+	}
+	if cfg.Paths.Manifest != "data/tournaments.yaml" {
+		t.Errorf("expected default manifest, got %s", cfg.Paths.Manifest)
+	}
+	if cfg.Processing.NonInteractive {
+		t.Error("expected interactive mode by default")
+	}
 }
 
 func TestLoadConfigMissing(t *testing.T) {
@@ -89,3 +107,310 @@ func TestLoadConfigMissing(t *testing.T) {
 		t.Error("expected error for missing config file")
 	}
 }
+
+func envLookup(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	cfg := &Config{
+		ELO:   ELOConfig{ProvisionalKFactor: 40},
+		Paths: PathsConfig{Database: "data/rankings.db"},
+	}
+
+	err := ApplyEnv(cfg, envLookup(map[string]string{
+		"ELO_ELO_PROVISIONAL_K_FACTOR":   "24",
+		"ELO_PATHS_DATABASE":             "/tmp/other.db",
+		"ELO_OUTPUT_TITLE":               "Weeklies",
+		"ELO_PROCESSING_NON_INTERACTIVE": "true",
+	}))
+	if err != nil {
+		t.Fatalf("ApplyEnv failed: %v", err)
+	}
+
+	if cfg.ELO.ProvisionalKFactor != 24 {
+		t.Errorf("expected provisional_k_factor 24, got %d", cfg.ELO.ProvisionalKFactor)
+	}
+	if cfg.Paths.Database != "/tmp/other.db" {
+		t.Errorf("expected database override, got %s", cfg.Paths.Database)
+	}
+	if cfg.Output.Title != "Weeklies" {
+		t.Errorf("expected title override, got %s", cfg.Output.Title)
+	}
+	if !cfg.Processing.NonInteractive {
+		t.Error("expected non_interactive override")
+	}
+}
+
+func TestApplyEnvReportsAllInvalidValues(t *testing.T) {
+	cfg := &Config{}
+	err := ApplyEnv(cfg, envLookup(map[string]string{
+		"ELO_ELO_PROVISIONAL_K_FACTOR":   "high",
+		"ELO_PROCESSING_NON_INTERACTIVE": "maybe",
+	}))
+	if err == nil {
+		t.Fatal("expected error for invalid values")
+	}
+	for _, name := range []string{"ELO_ELO_PROVISIONAL_K_FACTOR", "ELO_PROCESSING_NON_INTERACTIVE"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected %s in error, got: %v", name, err)
+		}
+	}
+}
+
+func TestLoadReportsEnvProblemsWithOthers(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"output": {"type": "ftp"}}`), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	t.Setenv("ELO_ELO_PROVISIONAL_K_FACTOR", "high")
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("expected bad overrides to wait for Validate, got: %v", err)
+	}
+	var verr *ValidationError
+	if !errors.As(cfg.Validate(), &verr) {
+		t.Fatal("expected ValidationError")
+	}
+	if !strings.HasPrefix(verr.Problems[0], "ELO_ELO_PROVISIONAL_K_FACTOR:") {
+		t.Errorf("expected the override to be reported first, got %v", verr.Problems)
+	}
+	if !strings.Contains(verr.Error(), "output.type:") || !strings.Contains(verr.Error(), "paths.pending_dir:") {
+		t.Errorf("expected the file's problems too, got %v", verr.Problems)
+	}
+}
+
+func TestLoadKeepsExplicitEnvZeros(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{}`), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	t.Setenv("ELO_OUTPUT_MIN_MATCHES", "0")
+	t.Setenv("ELO_ELO_INITIAL_RATING", "0")
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cfg.Output.MinMatches != 0 {
+		t.Errorf("expected min_matches 0 from the environment, got %d", cfg.Output.MinMatches)
+	}
+	var verr *ValidationError
+	if !errors.As(cfg.Validate(), &verr) {
+		t.Fatal("expected ValidationError")
+	}
+	if !strings.Contains(verr.Error(), "elo.initial_rating:") {
+		t.Errorf("expected initial_rating 0 to be reported, got %v", verr.Problems)
+	}
+}
+
+func validConfig(t *testing.T) *Config {
+	t.Helper()
+	root := t.TempDir()
+	return &Config{
+		ELO: ELOConfig{KFactor: 32, InitialRating: 1500},
+		Paths: PathsConfig{
+			PendingDir:   filepath.Join(root, "pending"),
+			ProcessedDir: filepath.Join(root, "processed"),
+			FailedDir:    filepath.Join(root, "failed"),
+			NeedsDateDir: filepath.Join(root, "needs-date"),
+			Database:     filepath.Join(root, "rankings.db"),
+			Output:       filepath.Join(root, "docs", "index.html"),
+		},
+		Output: OutputConfig{Type: "file"},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := validConfig(t).Validate(); err != nil {
+		t.Errorf("expected valid config, got: %v", err)
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	cfg := validConfig(t)
+	cfg.Paths.PendingDir = ""
+	cfg.Output.Type = "ftp"
+	cfg.Output.MatchupMinSets = -1
+	cfg.Output.ClubTopMembers = -2
+
+	// A regular file where the output dir should be
+	blocker := filepath.Join(t.TempDir(), "docs")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", blocker, err)
+	}
+	cfg.Paths.Output = filepath.Join(blocker, "index.html")
+
+	err := cfg.Validate()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+
+	want := []string{"paths.pending_dir", "paths.output", "output.type", "output.matchup_min_sets", "output.club_top_members"}
+	if len(verr.Problems) != len(want) {
+		t.Fatalf("expected %d problems, got %d: %v", len(want), len(verr.Problems), verr.Problems)
+	}
+	for i, field := range want {
+		if !strings.HasPrefix(verr.Problems[i], field+":") {
+			t.Errorf("expected problem %d to be about %s, got %q", i, field, verr.Problems[i])
+		}
+	}
+}
+
+func TestLoadYAMLProfiles(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `
+elo:
+  initial_rating: 1500
+paths:
+  pending_dir: data/pending
+  processed_dir: data/processed
+  failed_dir: data/failed
+  database: data/rankings.db
+  output: docs/index.html
+output:
+  title: Melee ELO Rankings
+profiles:
+  - name: weeklies
+    filter:
+      tiers: [weekly]
+    min_matches: 5
+  - name: regionals
+    title: Regionals Only
+    output: docs/regionals/index.html
+    elo:
+      initial_rating: 1200
+      provisional_k_factor: 32
+    filter:
+      tiers: [regional]
+      since: 2024-01-01
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	profiles := cfg.RankingProfiles()
+	if len(profiles) != 3 {
+		t.Fatalf("expected main plus 2 profiles, got %d", len(profiles))
+	}
+	main, weeklies, regionals := profiles[0], profiles[1], profiles[2]
+
+	if main.Name != MainProfile || main.Output != "docs/index.html" || main.MinMatches != 10 {
+		t.Errorf("unexpected main profile: %+v", main)
+	}
+	if weeklies.Output != filepath.Join("docs", "weeklies.html") {
+		t.Errorf("expected default output next to main index, got %s", weeklies.Output)
+	}
+	if weeklies.Title != "Melee ELO Rankings (weeklies)" {
+		t.Errorf("expected derived title, got %s", weeklies.Title)
+	}
+	if weeklies.MinMatches != 5 || weeklies.ELO.ProvisionalKFactor != 40 {
+		t.Errorf("unexpected weeklies settings: %+v", weeklies)
+	}
+	if regionals.ELO.InitialRating != 1200 || regionals.ELO.ProvisionalKFactor != 32 || regionals.ELO.EstablishedKFactor != 20 {
+		t.Errorf("expected regionals to override rating parameters, got %+v", regionals.ELO)
+	}
+	if regionals.Filter.Since != "2024-01-01" || regionals.MinMatches != 10 {
+		t.Errorf("unexpected regionals settings: %+v", regionals)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid config, got: %v", err)
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	configContent := `
+[elo]
+initial_rating = 1400
+
+[paths]
+database = "data/rankings.db"
+output = "docs/index.html"
+
+[[profiles]]
+name = "weeklies"
+[profiles.filter]
+tiers = ["weekly"]
+exclude_tournaments = [170676]
+until = 2024-12-31
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cfg.ELO.InitialRating != 1400 {
+		t.Errorf("expected initial_rating 1400, got %d", cfg.ELO.InitialRating)
+	}
+	if len(cfg.Profiles) != 1 || cfg.Profiles[0].Filter.ExcludeTournaments[0] != 170676 {
+		t.Fatalf("unexpected profiles: %+v", cfg.Profiles)
+	}
+	if cfg.Profiles[0].Filter.Until != "2024-12-31" {
+		t.Errorf("expected TOML date to decode as 2024-12-31, got %q", cfg.Profiles[0].Filter.Until)
+	}
+}
+
+func TestFilterIncludes(t *testing.T) {
+	date := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		filter FilterConfig
+		want   bool
+	}{
+		{"empty", FilterConfig{}, true},
+		{"tier match", FilterConfig{Tiers: []string{"weekly"}}, true},
+		{"tier mismatch", FilterConfig{Tiers: []string{"regional"}}, false},
+		{"listed", FilterConfig{Tournaments: []int{1, 2}}, true},
+		{"not listed", FilterConfig{Tournaments: []int{2}}, false},
+		{"excluded", FilterConfig{ExcludeTournaments: []int{1}}, false},
+		{"since inclusive", FilterConfig{Since: "2024-06-01"}, true},
+		{"before since", FilterConfig{Since: "2024-06-02"}, false},
+		{"after until", FilterConfig{Until: "2024-05-31"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Includes(1, date, "weekly"); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateProfiles(t *testing.T) {
+	cfg := validConfig(t)
+	cfg.Profiles = []ProfileConfig{
+		{Name: "weeklies"},
+		{Name: "weeklies", Output: filepath.Join(t.TempDir(), "other.html")},
+		{Name: "main"},
+		{Name: "dated", Filter: FilterConfig{Since: "June"}},
+	}
+
+	err := cfg.Validate()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	want := []string{"profiles[1].name", "profiles[2].name", "profiles[3].filter.since"}
+	if len(verr.Problems) != len(want) {
+		t.Fatalf("expected %d problems, got %d: %v", len(want), len(verr.Problems), verr.Problems)
+	}
+	for i, field := range want {
+		if !strings.HasPrefix(verr.Problems[i], field+":") {
+			t.Errorf("expected problem %d to be about %s, got %q", i, field, verr.Problems[i])
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// MainProfile is the name of the profile formed by the top-level settings.
+const MainProfile = "main"
+
+// ProfileConfig describes one published ranking. Unset fields are inherited
+// from the main profile.
+type ProfileConfig struct {
+	Name        string       `json:"name"`
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	Output      string       `json:"output"`
+	MinMatches  int          `json:"min_matches"`
+	ELO         ELOConfig    `json:"elo"`
+	Filter      FilterConfig `json:"filter"`
+}
+
+// FilterConfig selects the tournaments a profile is rated from. Empty lists
+// and dates don't filter anything.
+type FilterConfig struct {
+	Tiers              []string `json:"tiers"`
+	Tournaments        []int    `json:"tournaments"`
+	ExcludeTournaments []int    `json:"exclude_tournaments"`
+	Since              string   `json:"since"`
+	Until              string   `json:"until"`
+}
+
+// Includes reports whether a tournament passes the filter. Since and Until are
+// inclusive; tournaments without a date never pass a date bound.
+func (f FilterConfig) Includes(meleeID int, date time.Time, tier string) bool {
+	if len(f.Tournaments) > 0 && !containsInt(f.Tournaments, meleeID) {
+		return false
+	}
+	if containsInt(f.ExcludeTournaments, meleeID) {
+		return false
+	}
+	if len(f.Tiers) > 0 && !contains(f.Tiers, tier) {
+		return false
+	}
+	if since, err := time.Parse("2006-01-02", f.Since); err == nil && (date.IsZero() || date.Before(since)) {
+		return false
+	}
+	if until, err := time.Parse("2006-01-02", f.Until); err == nil && (date.IsZero() || date.After(until)) {
+		return false
+	}
+	return true
+}
+
+// RankingProfiles returns the main profile followed by the configured ones,
+// with unset fields filled in from the main profile. A profile without an
+// output path is written next to the main index as <name>.html.
+func (c *Config) RankingProfiles() []ProfileConfig {
+	main := ProfileConfig{
+		Name:        MainProfile,
+		Title:       c.Output.Title,
+		Description: c.Output.Description,
+		Output:      c.Paths.Output,
+		MinMatches:  c.Output.MinMatches,
+		ELO:         c.ELO,
+	}
+
+	profiles := []ProfileConfig{main}
+	for _, p := range c.Profiles {
+		if p.Title == "" {
+			p.Title = main.Title + " (" + p.Name + ")"
+		}
+		if p.Description == "" {
+			p.Description = main.Description
+		}
+		if p.Output == "" {
+			p.Output = filepath.Join(filepath.Dir(main.Output), p.Name+".html")
+		}
+		if p.MinMatches == 0 {
+			p.MinMatches = main.MinMatches
+		}
+		p.ELO = p.ELO.withDefaults(main.ELO)
+		profiles = append(profiles, p)
+	}
+	return profiles
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
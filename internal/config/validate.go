@@ -0,0 +1,192 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// OutputTypes lists the supported values of output.type.
+var OutputTypes = []string{"file"}
+
+// ValidationError lists every problem found in a config.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// Validate checks the config for missing or invalid fields and reports all of
+// them at once as a *ValidationError, after any ELO_* overrides Load could
+// not parse.
+func (c *Config) Validate() error {
+	problems := append([]string(nil), c.envProblems...)
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.ELO.InitialRating <= 0 {
+		addf("elo.initial_rating: must be positive, got %d", c.ELO.InitialRating)
+	}
+
+	required := []struct {
+		name  string
+		value string
+	}{
+		{"paths.pending_dir", c.Paths.PendingDir},
+		{"paths.processed_dir", c.Paths.ProcessedDir},
+		{"paths.failed_dir", c.Paths.FailedDir},
+		{"paths.needs_date_dir", c.Paths.NeedsDateDir},
+		{"paths.database", c.Paths.Database},
+		{"paths.output", c.Paths.Output},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			addf("%s: missing", r.name)
+		}
+	}
+
+	if c.Paths.Output != "" {
+		if err := checkWritableDir(filepath.Dir(c.Paths.Output)); err != nil {
+			addf("paths.output: %v", err)
+		}
+	}
+	if c.Paths.Database != "" && c.Paths.Database != ":memory:" {
+		if err := checkWritableDir(filepath.Dir(c.Paths.Database)); err != nil {
+			addf("paths.database: %v", err)
+		}
+	}
+
+	if !contains(OutputTypes, c.Output.Type) {
+		addf("output.type: unknown type %q, expected one of %s", c.Output.Type, strings.Join(OutputTypes, ", "))
+	}
+	if c.Output.MinMatches < 0 {
+		addf("output.min_matches: must not be negative, got %d", c.Output.MinMatches)
+	}
+	if c.Output.MatchupMinSets < 0 {
+		addf("output.matchup_min_sets: must not be negative, got %d", c.Output.MatchupMinSets)
+	}
+	if c.Output.ClubTopMembers < 0 {
+		addf("output.club_top_members: must not be negative, got %d", c.Output.ClubTopMembers)
+	}
+
+	problems = append(problems, c.validateProfiles()...)
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// validateProfiles checks the configured profiles along with the defaults
+// they inherit from the main profile.
+func (c *Config) validateProfiles() []string {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	names := map[string]bool{MainProfile: true}
+	for i, p := range c.Profiles {
+		field := fmt.Sprintf("profiles[%d]", i)
+		switch {
+		case p.Name == "":
+			addf("%s.name: missing", field)
+		case strings.ContainsAny(p.Name, `/\`):
+			addf("%s.name: %q must not contain path separators", field, p.Name)
+		case names[p.Name]:
+			addf("%s.name: duplicate profile %q", field, p.Name)
+		}
+		names[p.Name] = true
+
+		if p.MinMatches < 0 {
+			addf("%s.min_matches: must not be negative, got %d", field, p.MinMatches)
+		}
+		for _, bound := range []struct{ name, value string }{{"since", p.Filter.Since}, {"until", p.Filter.Until}} {
+			if bound.value == "" {
+				continue
+			}
+			if _, err := time.Parse("2006-01-02", bound.value); err != nil {
+				addf("%s.filter.%s: invalid date %q, expected YYYY-MM-DD", field, bound.name, bound.value)
+			}
+		}
+	}
+
+	outputs := make(map[string]string)
+	for i, p := range c.RankingProfiles() {
+		field := "elo"
+		if i > 0 {
+			field = fmt.Sprintf("profiles[%d].elo", i-1)
+		}
+		if p.ELO.ProvisionalKFactor < 0 || p.ELO.EstablishedKFactor < 0 {
+			addf("%s: k-factors must not be negative, got %d and %d", field, p.ELO.ProvisionalKFactor, p.ELO.EstablishedKFactor)
+		}
+		if p.ELO.ProvisionalMatches < 0 {
+			addf("%s.provisional_matches: must not be negative, got %d", field, p.ELO.ProvisionalMatches)
+		}
+		if i > 0 && p.ELO.InitialRating < 0 {
+			addf("%s.initial_rating: must not be negative, got %d", field, p.ELO.InitialRating)
+		}
+
+		if p.Output == "" {
+			continue
+		}
+		out := filepath.Clean(p.Output)
+		if other, ok := outputs[out]; ok {
+			addf("profile %q: output %s is already used by profile %q", p.Name, p.Output, other)
+		}
+		outputs[out] = p.Name
+		if i > 0 {
+			if err := checkWritableDir(filepath.Dir(out)); err != nil {
+				addf("profiles[%d].output: %v", i-1, err)
+			}
+		}
+	}
+
+	return problems
+}
+
+// checkWritableDir reports whether files can be created in dir. A missing dir
+// is fine as long as its closest existing parent is writable, since it will be
+// created on startup.
+func checkWritableDir(dir string) error {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", dir)
+			}
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return fmt.Errorf("%s does not exist", dir)
+		}
+		dir = parent
+	}
+
+	f, err := os.CreateTemp(dir, ".elo-write-check-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable", dir)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -5,20 +5,24 @@ import "math"
 type Calculator struct {
 	initialRating     int
 	dynamicKThreshold int
+	provisionalK      int
+	establishedK      int
 }
 
 func New(initialRating int) *Calculator {
 	return &Calculator{
 		initialRating:     initialRating,
 		dynamicKThreshold: 30,
+		provisionalK:      40,
+		establishedK:      20,
 	}
 }
 
 func (c *Calculator) GetDynamicKFactor(matchesPlayed int) int {
 	if matchesPlayed < c.dynamicKThreshold {
-		return 40
+		return c.provisionalK
 	}
-	return 20
+	return c.establishedK
 }
 
 // Calculate computes new ELO ratings after a match
@@ -69,3 +73,56 @@ func (c *Calculator) GetInitialRating() int {
 func (c *Calculator) SetDynamicKThreshold(threshold int) {
 	c.dynamicKThreshold = threshold
 }
+
+// SetKFactors sets the K-factor used before and after the dynamic K threshold.
+func (c *Calculator) SetKFactors(provisional, established int) {
+	c.provisionalK = provisional
+	c.establishedK = established
+}
+
+// performanceCap is how far above the strongest opponent a perfect score,
+// and below the weakest opponent a zero score, is rated. No finite rating
+// makes those results expected.
+const performanceCap = 800
+
+// PerformanceRating returns the rating a player performed at against
+// opponents with the given ratings: the rating at which their expected score
+// equals score. score counts wins as 1 and draws as 0.5. Results are kept
+// within performanceCap of the opponents, which is where perfect and zero
+// scores land. It returns 0 without opponents.
+func PerformanceRating(opponentRatings []int, score float64) int {
+	if len(opponentRatings) == 0 {
+		return 0
+	}
+	lo, hi := float64(opponentRatings[0]), float64(opponentRatings[0])
+	for _, r := range opponentRatings {
+		lo = math.Min(lo, float64(r))
+		hi = math.Max(hi, float64(r))
+	}
+	lo -= performanceCap
+	hi += performanceCap
+
+	// The expected score rises with the rating, so bisect for it
+	expected := func(rating float64) float64 {
+		total := 0.0
+		for _, r := range opponentRatings {
+			total += 1.0 / (1.0 + math.Pow(10, (float64(r)-rating)/400.0))
+		}
+		return total
+	}
+	if score >= expected(hi) {
+		return int(math.Round(hi))
+	}
+	if score <= expected(lo) {
+		return int(math.Round(lo))
+	}
+	for hi-lo > 0.01 {
+		mid := (lo + hi) / 2
+		if expected(mid) < score {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return int(math.Round((lo + hi) / 2))
+}
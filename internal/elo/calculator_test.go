@@ -62,6 +62,19 @@ func TestDynamicKFactor(t *testing.T) {
 	}
 }
 
+func TestCustomKFactors(t *testing.T) {
+	calc := New(1500)
+	calc.SetKFactors(32, 16)
+	calc.SetDynamicKThreshold(10)
+
+	if k := calc.GetDynamicKFactor(9); k != 32 {
+		t.Errorf("expected provisional K=32, got K=%d", k)
+	}
+	if k := calc.GetDynamicKFactor(10); k != 16 {
+		t.Errorf("expected established K=16, got K=%d", k)
+	}
+}
+
 func TestCalculate_Win(t *testing.T) {
 	calc := New(1500)
 
@@ -216,3 +229,28 @@ func TestCalculate_InitialRating(t *testing.T) {
 	}
 }
 
+
+func TestPerformanceRating(t *testing.T) {
+	tests := []struct {
+		name      string
+		opponents []int
+		score     float64
+		expected  int
+	}{
+		{"No opponents", nil, 0, 0},
+		{"Even record", []int{1500, 1700}, 1, 1600},
+		{"All wins are capped above the strongest opponent", []int{1500, 1600, 1700}, 3, 2500},
+		{"All losses are capped below the weakest opponent", []int{1500}, 0, 700},
+		{"Draw", []int{1550}, 0.5, 1550},
+		{"Two of three", []int{1400, 1500, 1600}, 2, 1627},
+		{"Lopsided field", []int{1000, 1000, 2000}, 2, 1564},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PerformanceRating(tt.opponents, tt.score); got != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package generator
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+// APISchemaVersion is the version of the static JSON API described in
+// docs/api.md. It is bumped whenever a field is removed or changes meaning;
+// new fields may be added without a bump.
+const APISchemaVersion = 1
+
+// apiDocument is the envelope every static API file is wrapped in.
+type apiDocument struct {
+	SchemaVersion int         `json:"schema_version"`
+	GeneratedAt   string      `json:"generated_at"`
+	Data          interface{} `json:"data"`
+}
+
+// RenderRankingsJSON returns api/rankings.json, built from the same data as
+// the rankings page.
+func (g *Generator) RenderRankingsJSON(rankings []storage.Ranking) ([]byte, error) {
+	return renderAPI(g.buildIndexData(rankings))
+}
+
+// RenderPlayerJSON returns api/players/<name>.json, built from the same data
+// as the player page.
+func (g *Generator) RenderPlayerJSON(playerName string, matches []storage.PlayerMatch, playerStats storage.Ranking) ([]byte, error) {
+	return renderAPI(g.buildPlayerData(playerName, matches, playerStats))
+}
+
+// RenderMatchupsJSON returns api/matchups.json, built from the same data as
+// the matchup matrix.
+func (g *Generator) RenderMatchupsJSON(matchups []storage.Matchup, players []string) ([]byte, error) {
+	return renderAPI(g.buildMatchupData(matchups, players))
+}
+
+// RenderTournamentsJSON returns api/tournaments.json.
+func (g *Generator) RenderTournamentsJSON(tournaments []storage.Tournament) ([]byte, error) {
+	return renderAPI(g.buildTournamentsData(tournaments))
+}
+
+func (g *Generator) GenerateRankingsJSON(rankings []storage.Ranking, outputPath string) error {
+	buf, err := g.RenderRankingsJSON(rankings)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, buf, 0644)
+}
+
+func (g *Generator) GeneratePlayerJSON(playerName string, matches []storage.PlayerMatch, playerStats storage.Ranking, outputPath string) error {
+	buf, err := g.RenderPlayerJSON(playerName, matches, playerStats)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, buf, 0644)
+}
+
+func (g *Generator) GenerateMatchupsJSON(matchups []storage.Matchup, players []string, outputPath string) error {
+	buf, err := g.RenderMatchupsJSON(matchups, players)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, buf, 0644)
+}
+
+func (g *Generator) GenerateTournamentsJSON(tournaments []storage.Tournament, outputPath string) error {
+	buf, err := g.RenderTournamentsJSON(tournaments)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, buf, 0644)
+}
+
+func renderAPI(data interface{}) ([]byte, error) {
+	buf, err := json.MarshalIndent(apiDocument{
+		SchemaVersion: APISchemaVersion,
+		GeneratedAt:   time.Now().UTC().Format(time.RFC3339),
+		Data:          data,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, '\n'), nil
+}
@@ -0,0 +1,112 @@
+package generator
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+func TestRenderAPI(t *testing.T) {
+	g := New("Rankings", "Weekly")
+
+	buf, err := g.RenderRankingsJSON([]storage.Ranking{
+		{Rank: 1, DisplayName: "Alice", CurrentELO: 1600, MatchesPlayed: 12, Wins: 9, Losses: 3, WinRate: 75},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rankings struct {
+		SchemaVersion int    `json:"schema_version"`
+		GeneratedAt   string `json:"generated_at"`
+		Data          map[string]interface{}
+	}
+	if err := json.Unmarshal(buf, &rankings); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf)
+	}
+	if rankings.SchemaVersion != APISchemaVersion || rankings.GeneratedAt == "" {
+		t.Errorf("envelope = %d %q", rankings.SchemaVersion, rankings.GeneratedAt)
+	}
+	if rankings.Data["title"] != "Rankings" {
+		t.Errorf("title = %v", rankings.Data["title"])
+	}
+	rows := rankings.Data["rankings"].([]interface{})
+	row := rows[0].(map[string]interface{})
+	if row["display_name"] != "Alice" || row["elo"] != 1600.0 {
+		t.Errorf("row = %v", row)
+	}
+	if _, ok := row["WinRateClass"]; ok {
+		t.Error("presentation fields should not be exported")
+	}
+
+	g.SetPerformances([]storage.TournamentPerformance{
+		{TournamentID: 3, Date: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Player: "Alice", Wins: 1, Performance: 1700, ELOBefore: 1580},
+		{TournamentID: 4, Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Player: "Alice", Wins: 1, Performance: 1500, ELOBefore: 1590},
+		{TournamentID: 4, Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Player: "Bob", Losses: 1, Performance: 1190},
+	})
+	g.SetPlayerProfiles([]storage.PlayerProfile{{DisplayName: "Alice", Region: "Paris", Main: "Fox"}})
+	buf, err = g.RenderPlayerJSON("Alice", []storage.PlayerMatch{
+		{DatePlayed: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Round: 2, OpponentName: "Bob", PlayerWins: 2, OpponentWins: 1, Result: "Win", PlayerELOBefore: 1590, PlayerELOAfter: 1600},
+	}, storage.Ranking{Rank: 1, CurrentELO: 1600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var player struct {
+		Data struct {
+			DisplayName string `json:"display_name"`
+			Region      string
+			Team        *string
+			Tournaments []map[string]interface{}
+			Matches     []map[string]interface{}
+		}
+	}
+	if err := json.Unmarshal(buf, &player); err != nil {
+		t.Fatal(err)
+	}
+	if player.Data.DisplayName != "Alice" || player.Data.Region != "Paris" || player.Data.Team != nil || len(player.Data.Matches) != 1 {
+		t.Fatalf("player = %+v", player.Data)
+	}
+	if m := player.Data.Matches[0]; m["date"] != "2024-03-01" || m["opponent"] != "Bob" {
+		t.Errorf("match = %v", m)
+	}
+	if got := player.Data.Tournaments; len(got) != 2 || got[0]["performance"] != 1700.0 || got[1]["tournament_id"] != 4.0 {
+		t.Errorf("tournaments = %v, want Alice's oldest first", got)
+	}
+
+	buf, err = g.RenderMatchupsJSON([]storage.Matchup{
+		{Player1: "Alice", Player2: "Bob", Player1Wins: 2, Player2Wins: 1, GamesPlayed: 3, Player1WinRate: 66.7},
+		{Player1: "Alice", Player2: "Carol", Player1Wins: 1, GamesPlayed: 1, Player1WinRate: 100},
+	}, []string{"Alice", "Bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var matchups struct {
+		Data MatchupData
+	}
+	if err := json.Unmarshal(buf, &matchups); err != nil {
+		t.Fatal(err)
+	}
+	pairs := matchups.Data.Matchups
+	if len(pairs) != 2 || pairs[0].Player2 != "Bob" || pairs[1].Player1 != "Bob" || pairs[1].Player1Wins != 1 {
+		t.Errorf("matchups = %+v, want both sides of the listed players only", pairs)
+	}
+
+	buf, err = g.RenderTournamentsJSON([]storage.Tournament{
+		{MeleeID: 1, Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Rated: true},
+		{MeleeID: 2, Name: "Weekly #2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tournaments struct {
+		Data TournamentsData
+	}
+	if err := json.Unmarshal(buf, &tournaments); err != nil {
+		t.Fatal(err)
+	}
+	got := tournaments.Data.Tournaments
+	if len(got) != 2 || got[0].MeleeID != 2 || got[0].Date != "" || got[1].Date != "2024-01-01" {
+		t.Errorf("tournaments = %+v, want newest first", got)
+	}
+}
@@ -0,0 +1,408 @@
+package generator
+
+import (
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+// topAverageSize is the number of players in the top average overlay.
+const topAverageSize = 10
+
+// RatingPoint is a player's rating at the end of a day of play.
+type RatingPoint struct {
+	Date time.Time
+	ELO  int
+}
+
+// ChartOverlay is a rating line that can be drawn over a player's chart.
+type ChartOverlay struct {
+	ID     string
+	Label  string
+	points []RatingPoint
+}
+
+// RatingHistories returns every player's rating after each day they played,
+// oldest first. Sets without ratings are skipped.
+func RatingHistories(matches []storage.TournamentMatch) map[string][]RatingPoint {
+	histories := make(map[string][]RatingPoint)
+	record := func(player string, date time.Time, elo int) {
+		h := histories[player]
+		if n := len(h); n > 0 && h[n-1].Date.Equal(date) {
+			h[n-1].ELO = elo
+			return
+		}
+		histories[player] = append(h, RatingPoint{Date: date, ELO: elo})
+	}
+	for _, m := range matches {
+		if m.Player1ELOAfter == 0 || m.Player2ELOAfter == 0 {
+			continue
+		}
+		record(m.Player1, m.Date, m.Player1ELOAfter)
+		record(m.Player2, m.Date, m.Player2ELOAfter)
+	}
+	return histories
+}
+
+// TopAverage returns the average rating of the n best rated players after
+// each day of play. Players count from their first rated set.
+func TopAverage(histories map[string][]RatingPoint, n int) []RatingPoint {
+	var dates []time.Time
+	seen := make(map[time.Time]bool)
+	for _, h := range histories {
+		for _, p := range h {
+			if !seen[p.Date] {
+				seen[p.Date] = true
+				dates = append(dates, p.Date)
+			}
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	next := make(map[string]int, len(histories))
+	current := make(map[string]int, len(histories))
+	average := make([]RatingPoint, 0, len(dates))
+	for _, date := range dates {
+		for player, h := range histories {
+			if i := next[player]; i < len(h) && h[i].Date.Equal(date) {
+				current[player] = h[i].ELO
+				next[player] = i + 1
+			}
+		}
+		ratings := make([]int, 0, len(current))
+		for _, elo := range current {
+			ratings = append(ratings, elo)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(ratings)))
+		if len(ratings) > n {
+			ratings = ratings[:n]
+		}
+		sum := 0
+		for _, elo := range ratings {
+			sum += elo
+		}
+		average = append(average, RatingPoint{Date: date, ELO: (sum + len(ratings)/2) / len(ratings)})
+	}
+	return average
+}
+
+// chartOverlays returns the lines a player's chart can be compared with: the
+// top average, then every other comparable player by name. Lines that only
+// start after the player's last set are left out.
+func (g *Generator) chartOverlays(playerName string, matches []storage.PlayerMatch) []ChartOverlay {
+	if g.ratingHistories == nil || len(matches) == 0 {
+		return nil
+	}
+	end := matches[len(matches)-1].DatePlayed
+	overlays := []ChartOverlay{{
+		ID:     "top",
+		Label:  fmt.Sprintf("Top %d average", topAverageSize),
+		points: g.topAverage,
+	}}
+	names := make([]string, 0, len(g.comparePlayers))
+	for _, name := range g.comparePlayers {
+		h := g.ratingHistories[name]
+		if name != playerName && len(h) > 0 && !h[0].Date.After(end) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		overlays = append(overlays, ChartOverlay{
+			ID:     "player-" + g.slugs.Slug(name),
+			Label:  name,
+			points: g.ratingHistories[name],
+		})
+	}
+	return overlays
+}
+
+// chartTournament is one marker on the rating chart: the sets a player
+// played at one tournament.
+type chartTournament struct {
+	title  string
+	date   time.Time
+	before int
+	after  int
+	sets   []storage.PlayerMatch
+}
+
+func chartTournaments(matches []storage.PlayerMatch) []chartTournament {
+	var tournaments []chartTournament
+	for _, m := range matches {
+		n := len(tournaments)
+		if n == 0 || tournaments[n-1].sets[0].TournamentID != m.TournamentID {
+			tournaments = append(tournaments, chartTournament{
+				title:  tournamentTitle(m.TournamentID, m.TournamentName),
+				date:   m.DatePlayed,
+				before: m.PlayerELOBefore,
+			})
+			n++
+		}
+		tournaments[n-1].after = m.PlayerELOAfter
+		tournaments[n-1].sets = append(tournaments[n-1].sets, m)
+	}
+	return tournaments
+}
+
+// tooltip lists the tournament's rating change and every set in it.
+func (t chartTournament) tooltip() string {
+	lines := []string{
+		fmt.Sprintf("%s, %s", t.title, t.date.Format("Jan 2, 2006")),
+		fmt.Sprintf("%d → %d (%+d)", t.before, t.after, t.after-t.before),
+	}
+	for _, s := range t.sets {
+		lines = append(lines, fmt.Sprintf("vs %s %d-%d (%+d)",
+			s.OpponentName, s.PlayerWins, s.OpponentWins, s.PlayerELOAfter-s.PlayerELOBefore))
+	}
+	return template.HTMLEscapeString(strings.Join(lines, "\n"))
+}
+
+// generateELOChart plots a player's rating after each tournament against
+// the date it was played. Overlays are drawn hidden for the page to show.
+func generateELOChart(matches []storage.PlayerMatch, overlays []ChartOverlay) string {
+	if len(matches) == 0 {
+		return "<p>No match data available</p>"
+	}
+
+	width := 800
+	height := 300
+	padding := 50
+
+	chartWidth := width - 2*padding
+	chartHeight := height - 2*padding
+
+	tournaments := chartTournaments(matches)
+	start := tournaments[0].date
+	end := tournaments[len(tournaments)-1].date
+	overlayPoints := make([][]RatingPoint, len(overlays))
+	for i, o := range overlays {
+		overlayPoints[i] = clipRatings(o.points, start, end)
+	}
+
+	minELO := matches[0].PlayerELOBefore
+	maxELO := matches[0].PlayerELOBefore
+	peak := matches[0]
+	for _, m := range matches {
+		if m.PlayerELOAfter < minELO {
+			minELO = m.PlayerELOAfter
+		}
+		if m.PlayerELOAfter > maxELO {
+			maxELO = m.PlayerELOAfter
+		}
+		if m.PlayerELOAfter > peak.PlayerELOAfter {
+			peak = m
+		}
+	}
+	// The top average stays in view; other players are clipped to the chart
+	if len(overlays) > 0 && overlays[0].ID == "top" {
+		for _, p := range overlayPoints[0] {
+			if p.ELO < minELO {
+				minELO = p.ELO
+			}
+			if p.ELO > maxELO {
+				maxELO = p.ELO
+			}
+		}
+	}
+
+	eloRange := maxELO - minELO
+	if eloRange == 0 {
+		eloRange = 100
+	}
+	minELO -= eloRange / 10
+	maxELO += eloRange / 10
+	eloRange = maxELO - minELO
+
+	// A single day is drawn in the middle rather than dividing by zero
+	span := end.Sub(start)
+	x := func(date time.Time) int {
+		if span == 0 {
+			return padding + chartWidth/2
+		}
+		return padding + int(int64(chartWidth)*int64(date.Sub(start))/int64(span))
+	}
+	y := func(elo int) int {
+		return height - padding - ((elo - minELO) * chartHeight / eloRange)
+	}
+
+	points := make([]string, len(tournaments))
+	markers := ""
+	for i, t := range tournaments {
+		points[i] = fmt.Sprintf("%d,%d", x(t.date), y(t.after))
+		color := "#fbbf24"
+		if t.after > t.before {
+			color = "#4ade80"
+		} else if t.after < t.before {
+			color = "#f87171"
+		}
+		markers += fmt.Sprintf(`<circle cx="%d" cy="%d" r="5" fill="%s"><title>%s</title></circle>`,
+			x(t.date), y(t.after), color, t.tooltip())
+	}
+
+	overlayLines := ""
+	for i, o := range overlays {
+		if len(overlayPoints[i]) == 0 {
+			continue
+		}
+		line := make([]string, len(overlayPoints[i]))
+		for j, p := range overlayPoints[i] {
+			line[j] = fmt.Sprintf("%d,%d", x(p.Date), y(p.ELO))
+		}
+		overlayLines += fmt.Sprintf(`<g class="chart-overlay" data-overlay="%s" style="display:none" clip-path="url(#plotArea)"><polyline points="%s" fill="none" stroke="#a0a0a0" stroke-width="2" stroke-dasharray="6 4" /></g>`,
+			template.HTMLEscapeString(o.ID), strings.Join(line, " "))
+	}
+
+	yAxisLabels := ""
+	for i := 0; i <= 5; i++ {
+		value := minELO + (eloRange * i / 5)
+		y := height - padding - (chartHeight * i / 5)
+		yAxisLabels += fmt.Sprintf(`<text x="%d" y="%d" text-anchor="end" fill="#888" font-size="12">%d</text>`, padding-10, int(y)+4, value)
+	}
+
+	// Label months over long spans and days over short ones
+	xAxisLabels := ""
+	layout := "Jan 2"
+	if span > 90*24*time.Hour {
+		layout = "Jan 2006"
+	}
+	ticks := 5
+	if span == 0 {
+		ticks = 0
+	}
+	for i := 0; i <= ticks; i++ {
+		date := start.Add(span * time.Duration(i) / time.Duration(max(ticks, 1)))
+		xAxisLabels += fmt.Sprintf(`<text x="%d" y="%d" text-anchor="middle" fill="#888" font-size="12">%s</text>`,
+			x(date), height-padding+20, date.Format(layout))
+	}
+
+	return fmt.Sprintf(`<svg viewBox="0 0 %d %d" style="width:100%%;height:100%%;">
+		<defs>
+			<linearGradient id="lineGradient" x1="0%%" y1="0%%" x2="100%%" y2="0%%">
+				<stop offset="0%%" style="stop-color:#667eea" />
+				<stop offset="100%%" style="stop-color:#764ba2" />
+			</linearGradient>
+			<clipPath id="plotArea"><rect x="%d" y="%d" width="%d" height="%d" /></clipPath>
+		</defs>
+		<rect x="0" y="0" width="%d" height="%d" fill="rgba(255,255,255,0.02)" rx="8" />
+		<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="rgba(255,255,255,0.1)" stroke-width="1" />
+		<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="rgba(255,255,255,0.1)" stroke-width="1" />
+		%s
+		%s
+		<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="rgba(251,191,36,0.4)" stroke-width="1" stroke-dasharray="4" />
+		<text x="%d" y="%d" text-anchor="end" fill="#fbbf24" font-size="12">Peak %d (%s)</text>
+		%s
+		<polyline points="%s" fill="none" stroke="url(#lineGradient)" stroke-width="3" stroke-linecap="round" stroke-linejoin="round" />
+		%s
+		<text x="%d" y="%d" text-anchor="middle" fill="#667eea" font-size="14" font-weight="600">ELO Over Time</text>
+	</svg>`,
+		width, height,
+		padding, padding, chartWidth, chartHeight,
+		width, height,
+		padding, padding, padding, height-padding,
+		padding, height-padding, width-padding, height-padding,
+		yAxisLabels,
+		xAxisLabels,
+		padding, y(peak.PlayerELOAfter), width-padding, y(peak.PlayerELOAfter),
+		width-padding, y(peak.PlayerELOAfter)-6, peak.PlayerELOAfter, peak.DatePlayed.Format("Jan 2, 2006"),
+		overlayLines,
+		strings.Join(points, " "),
+		markers,
+		width/2, padding-15,
+	)
+}
+
+// generateRankChart plots a player's rank in each ranking snapshot, with
+// first place at the top.
+func generateRankChart(snapshots []storage.SnapshotEntry) string {
+	if len(snapshots) == 0 {
+		return ""
+	}
+
+	width := 800
+	height := 200
+	padding := 50
+
+	chartWidth := width - 2*padding
+	chartHeight := height - 2*padding
+
+	worst := 1
+	for _, e := range snapshots {
+		if e.Rank > worst {
+			worst = e.Rank
+		}
+	}
+	start := snapshots[0].Date
+	span := snapshots[len(snapshots)-1].Date.Sub(start)
+	x := func(date time.Time) int {
+		if span == 0 {
+			return padding + chartWidth/2
+		}
+		return padding + int(int64(chartWidth)*int64(date.Sub(start))/int64(span))
+	}
+	y := func(rank int) int {
+		if worst == 1 {
+			return padding
+		}
+		return padding + (rank-1)*chartHeight/(worst-1)
+	}
+
+	bottomLabel := ""
+	if worst > 1 {
+		bottomLabel = fmt.Sprintf("#%d", worst)
+	}
+
+	points := make([]string, len(snapshots))
+	markers := ""
+	for i, e := range snapshots {
+		points[i] = fmt.Sprintf("%d,%d", x(e.Date), y(e.Rank))
+		markers += fmt.Sprintf(`<circle cx="%d" cy="%d" r="4" fill="#667eea"><title>%s: #%d (%d)</title></circle>`,
+			x(e.Date), y(e.Rank), e.Date.Format("Jan 2, 2006"), e.Rank, e.ELO)
+	}
+
+	return fmt.Sprintf(`<svg viewBox="0 0 %d %d" style="width:100%%;height:100%%;">
+		<rect x="0" y="0" width="%d" height="%d" fill="rgba(255,255,255,0.02)" rx="8" />
+		<text x="%d" y="%d" text-anchor="end" fill="#888" font-size="12">#1</text>
+		<text x="%d" y="%d" text-anchor="end" fill="#888" font-size="12">%s</text>
+		<text x="%d" y="%d" text-anchor="start" fill="#888" font-size="12">%s</text>
+		<text x="%d" y="%d" text-anchor="end" fill="#888" font-size="12">%s</text>
+		<polyline points="%s" fill="none" stroke="#667eea" stroke-width="3" stroke-linecap="round" stroke-linejoin="round" />
+		%s
+	</svg>`,
+		width, height,
+		width, height,
+		padding-10, padding+4,
+		padding-10, height-padding+4, bottomLabel,
+		padding, height-padding+25, start.Format("Jan 2, 2006"),
+		width-padding, height-padding+25, snapshots[len(snapshots)-1].Date.Format("Jan 2, 2006"),
+		strings.Join(points, " "),
+		markers,
+	)
+}
+
+// clipRatings returns the points of a rating line between start and end,
+// running from the rating held at start to the one held at end.
+func clipRatings(points []RatingPoint, start, end time.Time) []RatingPoint {
+	var clipped []RatingPoint
+	for i, p := range points {
+		if p.Date.After(end) {
+			break
+		}
+		if p.Date.Before(start) {
+			if i+1 == len(points) || points[i+1].Date.After(start) {
+				clipped = append(clipped, RatingPoint{Date: start, ELO: p.ELO})
+			}
+			continue
+		}
+		clipped = append(clipped, p)
+	}
+	// Ratings hold until the next set
+	if n := len(clipped); n > 0 && clipped[n-1].Date.Before(end) {
+		clipped = append(clipped, RatingPoint{Date: end, ELO: clipped[n-1].ELO})
+	}
+	return clipped
+}
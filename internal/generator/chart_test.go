@@ -0,0 +1,123 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+func day(d int) time.Time {
+	return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestRatingHistories(t *testing.T) {
+	matches := []storage.TournamentMatch{
+		{Date: day(1), Player1: "Alice", Player2: "Bob", Player1ELOAfter: 1516, Player2ELOAfter: 1484},
+		{Date: day(1), Player1: "Alice", Player2: "Carol", Player1ELOAfter: 1530, Player2ELOAfter: 1486},
+		// Unrated tournaments have no ratings
+		{Date: day(5), Player1: "Bob", Player2: "Carol"},
+		{Date: day(8), Player1: "Bob", Player2: "Alice", Player1ELOAfter: 1500, Player2ELOAfter: 1514},
+	}
+	histories := RatingHistories(matches)
+
+	alice := histories["Alice"]
+	if len(alice) != 2 || alice[0].ELO != 1530 || !alice[1].Date.Equal(day(8)) || alice[1].ELO != 1514 {
+		t.Errorf("expected Alice's rating at the end of each day, got %+v", alice)
+	}
+	if carol := histories["Carol"]; len(carol) != 1 {
+		t.Errorf("expected unrated sets to be skipped, got %+v", carol)
+	}
+
+	average := TopAverage(histories, 2)
+	want := []RatingPoint{{day(1), 1508}, {day(8), 1507}}
+	if len(average) != len(want) {
+		t.Fatalf("expected %d points, got %+v", len(want), average)
+	}
+	for i := range want {
+		if !average[i].Date.Equal(want[i].Date) || average[i].ELO != want[i].ELO {
+			t.Errorf("point %d: expected %+v, got %+v", i, want[i], average[i])
+		}
+	}
+}
+
+func TestClipRatings(t *testing.T) {
+	points := []RatingPoint{{day(1), 1500}, {day(3), 1520}, {day(6), 1510}, {day(9), 1540}}
+	got := clipRatings(points, day(2), day(7))
+	want := []RatingPoint{{day(2), 1500}, {day(3), 1520}, {day(6), 1510}, {day(7), 1510}}
+	if len(got) != len(want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	for i := range want {
+		if !got[i].Date.Equal(want[i].Date) || got[i].ELO != want[i].ELO {
+			t.Errorf("point %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+
+	if got := clipRatings(points, day(10), day(12)); len(got) != 2 || got[0].ELO != 1540 || got[1].ELO != 1540 {
+		t.Errorf("expected the last rating to carry over, got %+v", got)
+	}
+}
+
+func TestELOChartUsesDates(t *testing.T) {
+	matches := []storage.PlayerMatch{
+		{DatePlayed: day(1), TournamentID: 1, TournamentName: "Weekly #1", OpponentName: "Bob", PlayerWins: 2, PlayerELOBefore: 1500, PlayerELOAfter: 1516},
+		{DatePlayed: day(1), TournamentID: 1, TournamentName: "Weekly #1", OpponentName: "Carol", OpponentWins: 2, PlayerELOBefore: 1516, PlayerELOAfter: 1501},
+		{DatePlayed: day(29), TournamentID: 2, OpponentName: "Bob", PlayerWins: 2, PlayerELOBefore: 1501, PlayerELOAfter: 1514},
+	}
+	overlays := []ChartOverlay{{ID: "top", Label: "Top 10 average", points: []RatingPoint{{day(1), 1600}}}}
+	chart := generateELOChart(matches, overlays)
+
+	for _, want := range []string{
+		// The tournaments sit at both ends of the axis
+		`<circle cx="50" cy=`,
+		`<circle cx="750" cy=`,
+		"Weekly #1, Jan 1, 2024\n1500 → 1501 (+1)\nvs Bob 2-0 (+16)\nvs Carol 0-2 (-15)",
+		"Tournament #2, Jan 29, 2024",
+		">Jan 1<",
+		"Peak 1516 (Jan 1, 2024)",
+		`data-overlay="top" style="display:none"`,
+	} {
+		if !strings.Contains(chart, want) {
+			t.Errorf("expected chart to contain %q", want)
+		}
+	}
+}
+
+func TestChartOverlays(t *testing.T) {
+	g := New("Rankings", "")
+	g.SetRatingHistory([]storage.TournamentMatch{
+		{Date: day(1), Player1: "Alice", Player2: "Bob", Player1ELOAfter: 1516, Player2ELOAfter: 1484},
+		{Date: day(9), Player1: "Dave", Player2: "Bob", Player1ELOAfter: 1516, Player2ELOAfter: 1468},
+	}, []string{"Bob", "Alice", "Dave"})
+
+	matches := []storage.PlayerMatch{{DatePlayed: day(1), OpponentName: "Bob", PlayerELOBefore: 1500, PlayerELOAfter: 1516}}
+	overlays := g.chartOverlays("Alice", matches)
+
+	// Dave only started playing after Alice's last set
+	if len(overlays) != 2 || overlays[0].ID != "top" || overlays[1].ID != "player-bob" || overlays[1].Label != "Bob" {
+		t.Errorf("unexpected overlays: %+v", overlays)
+	}
+}
+
+func TestRankChart(t *testing.T) {
+	if chart := generateRankChart(nil); chart != "" {
+		t.Errorf("expected no chart without snapshots, got %q", chart)
+	}
+
+	chart := generateRankChart([]storage.SnapshotEntry{
+		{Date: day(1), Rank: 4, ELO: 1520},
+		{Date: day(8), Rank: 1, ELO: 1580},
+	})
+	for _, want := range []string{
+		// First place is drawn at the top and the worst rank at the bottom
+		`<circle cx="50" cy="150" r="4" fill="#667eea"><title>Jan 1, 2024: #4 (1520)</title></circle>`,
+		`<circle cx="750" cy="50" r="4"`,
+		">#4<",
+	} {
+		if !strings.Contains(chart, want) {
+			t.Errorf("expected chart to contain %q", want)
+		}
+	}
+}
@@ -9,6 +9,31 @@ import (
 type Generator struct {
 	title       string
 	description string
+	profiles    []ProfileLink
+	slugs       *Slugs
+	playerPages map[string]bool
+	// matchupMinSets greys out smaller samples in the matchup matrix
+	matchupMinSets int
+	// ratingHistories and topAverage are drawn over player charts
+	ratingHistories map[string][]RatingPoint
+	topAverage      []RatingPoint
+	comparePlayers  []string
+	// previousRanking and rankHistories come from the ranking snapshots
+	previousRanking map[int64]storage.SnapshotEntry
+	rankHistories   map[int64][]storage.SnapshotEntry
+	// performances are listed per tournament on player pages
+	performances map[string][]storage.TournamentPerformance
+	// playerProfiles are shown under the player's name
+	playerProfiles map[string]storage.PlayerProfile
+	// regions and clubs each have a leaderboard, linked from the index
+	regions, clubs         []string
+	regionSlugs, clubSlugs *Slugs
+}
+
+// ProfileLink is a ranking page listed in the navigation of every index.
+type ProfileLink struct {
+	Title  string
+	Output string
 }
 
 func New(title, description string) *Generator {
@@ -18,8 +43,100 @@ func New(title, description string) *Generator {
 	}
 }
 
+// SetProfiles sets the ranking pages to cross-link. The navigation is only
+// shown when there is more than one.
+func (g *Generator) SetProfiles(profiles []ProfileLink) {
+	g.profiles = profiles
+}
+
+// SetSlugs sets the slugs used for player file names and links. Without
+// them every name is slugified on its own.
+func (g *Generator) SetSlugs(slugs *Slugs) {
+	g.slugs = slugs
+}
+
+// SetMatchupMinSets sets the number of sets below which a matchup matrix
+// cell is greyed out.
+func (g *Generator) SetMatchupMinSets(minSets int) {
+	g.matchupMinSets = minSets
+}
+
+// SetPlayerPages limits links to player pages to the given players. By
+// default every player is linked.
+func (g *Generator) SetPlayerPages(names []string) {
+	g.playerPages = make(map[string]bool, len(names))
+	for _, name := range names {
+		g.playerPages[name] = true
+	}
+}
+
+// SetRatingHistory sets the rated sets that player charts are compared
+// against: the top average over time and each of the given players.
+func (g *Generator) SetRatingHistory(matches []storage.TournamentMatch, players []string) {
+	g.ratingHistories = RatingHistories(matches)
+	g.topAverage = TopAverage(g.ratingHistories, topAverageSize)
+	g.comparePlayers = players
+}
+
+// SetPreviousRanking sets the snapshot the index shows movement against.
+// Without one no movement is shown.
+func (g *Generator) SetPreviousRanking(entries []storage.SnapshotEntry) {
+	if len(entries) == 0 {
+		g.previousRanking = nil
+		return
+	}
+	g.previousRanking = make(map[int64]storage.SnapshotEntry, len(entries))
+	for _, e := range entries {
+		g.previousRanking[e.PlayerID] = e
+	}
+}
+
+// SetRankHistory sets the snapshots charted on player pages. Entries may
+// cover any number of players, oldest first.
+func (g *Generator) SetRankHistory(entries []storage.SnapshotEntry) {
+	g.rankHistories = make(map[int64][]storage.SnapshotEntry)
+	for _, e := range entries {
+		g.rankHistories[e.PlayerID] = append(g.rankHistories[e.PlayerID], e)
+	}
+}
+
+// SetPerformances sets the tournament performances listed on player pages.
+// Entries may cover any number of players, oldest first.
+func (g *Generator) SetPerformances(entries []storage.TournamentPerformance) {
+	g.performances = make(map[string][]storage.TournamentPerformance)
+	for _, e := range entries {
+		g.performances[e.Player] = append(g.performances[e.Player], e)
+	}
+}
+
+// SetPlayerProfiles sets the published player profiles, as returned by
+// storage.GetPlayerProfiles.
+func (g *Generator) SetPlayerProfiles(profiles []storage.PlayerProfile) {
+	g.playerProfiles = make(map[string]storage.PlayerProfile, len(profiles))
+	for _, p := range profiles {
+		g.playerProfiles[p.DisplayName] = p
+	}
+}
+
+// SetLeaderboards sets the regions and clubs that have a leaderboard page,
+// as returned by storage.GetGroups.
+func (g *Generator) SetLeaderboards(regions, clubs []string) {
+	g.regions, g.clubs = regions, clubs
+	// The club table is clubs/index.html, so no club may be named index
+	g.regionSlugs, g.clubSlugs = NewSlugs(regions), newSlugs(clubs, map[string]bool{"index": true})
+}
+
+// playerLink links a player's page from a page that reaches the player
+// pages through prefix.
+func (g *Generator) playerLink(name, prefix string) PlayerLink {
+	if g.playerPages != nil && !g.playerPages[name] {
+		return PlayerLink{Name: name}
+	}
+	return PlayerLink{Name: name, Href: prefix + g.slugs.Slug(name) + ".html"}
+}
+
 func (g *Generator) Generate(rankings []storage.Ranking, outputPath string) error {
-	buf, err := g.renderIndex(rankings)
+	buf, err := g.RenderIndex(rankings, outputPath)
 	if err != nil {
 		return err
 	}
@@ -27,7 +144,7 @@ func (g *Generator) Generate(rankings []storage.Ranking, outputPath string) erro
 }
 
 func (g *Generator) GeneratePlayerPage(playerName string, matches []storage.PlayerMatch, playerStats storage.Ranking, outputPath string) error {
-	buf, err := g.renderPlayer(playerName, matches, playerStats)
+	buf, err := g.RenderPlayerPage(playerName, matches, playerStats)
 	if err != nil {
 		return err
 	}
@@ -35,9 +152,122 @@ func (g *Generator) GeneratePlayerPage(playerName string, matches []storage.Play
 }
 
 func (g *Generator) GenerateMatchupMatrix(matchups []storage.Matchup, players []string, outputPath string) error {
-	buf, err := g.renderMatchup(matchups, players)
+	buf, err := g.RenderMatchupMatrix(matchups, players)
 	if err != nil {
 		return err
 	}
 	return os.WriteFile(outputPath, buf, 0644)
 }
+
+func (g *Generator) GenerateTournamentPage(tournament storage.Tournament, matches []storage.TournamentMatch, outputPath string) error {
+	buf, err := g.RenderTournamentPage(tournament, matches)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, buf, 0644)
+}
+
+func (g *Generator) GenerateTournamentList(tournaments []storage.Tournament, outputPath string) error {
+	buf, err := g.RenderTournamentList(tournaments)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, buf, 0644)
+}
+
+func (g *Generator) GenerateRecords(matches []storage.TournamentMatch, outputPath string) error {
+	buf, err := g.RenderRecords(matches)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, buf, 0644)
+}
+
+func (g *Generator) GenerateHeadToHeadPage(h HeadToHead, outputPath string) error {
+	buf, err := g.RenderHeadToHeadPage(h)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, buf, 0644)
+}
+
+// GenerateLeaderboard writes the leaderboard of a region or club. Links
+// assume outputPath is where LeaderboardPath puts it.
+func (g *Generator) GenerateLeaderboard(group storage.PlayerGroup, name string, rankings []storage.Ranking, outputPath string) error {
+	buf, err := g.RenderLeaderboard(group, name, rankings)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, buf, 0644)
+}
+
+// GenerateClubTable writes the club table, which links assume is at
+// clubs/index.html.
+func (g *Generator) GenerateClubTable(clubs []storage.ClubRanking, topMembers int, outputPath string) error {
+	buf, err := g.RenderClubTable(clubs, topMembers)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, buf, 0644)
+}
+
+// GenerateRedirect writes a page at outputPath that sends browsers on to
+// target, a URL relative to it.
+func (g *Generator) GenerateRedirect(target, outputPath string) error {
+	buf, err := executeTemplate("templates/redirect.tmpl", struct{ Target string }{target})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, buf, 0644)
+}
+
+// RenderIndex returns the rankings page as it would be written to
+// outputPath, which is used to resolve profile links.
+func (g *Generator) RenderIndex(rankings []storage.Ranking, outputPath string) ([]byte, error) {
+	return g.renderIndex(rankings, outputPath)
+}
+
+// RenderPlayerPage returns a player's page without writing it.
+func (g *Generator) RenderPlayerPage(playerName string, matches []storage.PlayerMatch, playerStats storage.Ranking) ([]byte, error) {
+	return g.renderPlayer(playerName, matches, playerStats)
+}
+
+// RenderMatchupMatrix returns the matchup matrix page without writing it.
+func (g *Generator) RenderMatchupMatrix(matchups []storage.Matchup, players []string) ([]byte, error) {
+	return g.renderMatchup(matchups, players)
+}
+
+// RenderTournamentPage returns a tournament's results page without writing it.
+func (g *Generator) RenderTournamentPage(tournament storage.Tournament, matches []storage.TournamentMatch) ([]byte, error) {
+	return g.renderTournament(tournament, matches)
+}
+
+// RenderTournamentList returns the list of tournaments, newest first,
+// without writing it.
+func (g *Generator) RenderTournamentList(tournaments []storage.Tournament) ([]byte, error) {
+	return g.renderTournaments(tournaments)
+}
+
+// RenderRecords returns the records page computed from the given sets,
+// oldest first, without writing it.
+func (g *Generator) RenderRecords(matches []storage.TournamentMatch) ([]byte, error) {
+	return g.renderRecords(matches)
+}
+
+// RenderLeaderboard returns the leaderboard of a region or club, from
+// rankings of its players only, without writing it.
+func (g *Generator) RenderLeaderboard(group storage.PlayerGroup, name string, rankings []storage.Ranking) ([]byte, error) {
+	return g.renderLeaderboard(group, name, rankings)
+}
+
+// RenderClubTable returns the club table without writing it. topMembers is
+// how many players each club's average is taken over.
+func (g *Generator) RenderClubTable(clubs []storage.ClubRanking, topMembers int) ([]byte, error) {
+	return g.renderClubTable(clubs, topMembers)
+}
+
+// RenderHeadToHeadPage returns the page of every set between two players
+// without writing it.
+func (g *Generator) RenderHeadToHeadPage(h HeadToHead) ([]byte, error) {
+	return g.renderH2H(h)
+}
@@ -0,0 +1,196 @@
+package generator
+
+import (
+	"fmt"
+	"html/template"
+	"sort"
+	"time"
+
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+// HeadToHead is every set two players have played against each other, with
+// Player1 on the left of each set.
+type HeadToHead struct {
+	Player1 string
+	Player2 string
+	Sets    []storage.TournamentMatch
+}
+
+// H2HData is the data passed to the head-to-head template.
+type H2HData struct {
+	Player1      PlayerLink
+	Player2      PlayerLink
+	Timestamp    string
+	Player1Sets  int
+	Player2Sets  int
+	Player1Games int
+	Player2Games int
+	GapChartHTML template.HTML
+	Sets         []H2HSetRow
+}
+
+// H2HSetRow is one set between the two players. Gap is Player1's rating
+// minus Player2's going into the set.
+type H2HSetRow struct {
+	Date             string
+	TournamentID     int
+	TournamentTitle  string
+	Round            int
+	Player1Wins      int
+	Player2Wins      int
+	Player1ELOBefore int
+	Player2ELOBefore int
+	Gap              int
+	Winner           int
+}
+
+// HeadToHeads groups matches by the pair of players. Each pair is ordered by
+// slug, so its page name does not depend on who was player 1 in a set.
+func (g *Generator) HeadToHeads(matches []storage.TournamentMatch) []HeadToHead {
+	index := make(map[[2]string]int)
+	var pairs []HeadToHead
+	for _, m := range matches {
+		if g.slugs.Slug(m.Player2) < g.slugs.Slug(m.Player1) {
+			m.Player1, m.Player2 = m.Player2, m.Player1
+			m.Player1Wins, m.Player2Wins = m.Player2Wins, m.Player1Wins
+			m.Player1ELOBefore, m.Player2ELOBefore = m.Player2ELOBefore, m.Player1ELOBefore
+			m.Player1ELOAfter, m.Player2ELOAfter = m.Player2ELOAfter, m.Player1ELOAfter
+		}
+		key := [2]string{m.Player1, m.Player2}
+		i, ok := index[key]
+		if !ok {
+			i = len(pairs)
+			index[key] = i
+			pairs = append(pairs, HeadToHead{Player1: m.Player1, Player2: m.Player2})
+		}
+		pairs[i].Sets = append(pairs[i].Sets, m)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return g.HeadToHeadSlug(pairs[i].Player1, pairs[i].Player2) < g.HeadToHeadSlug(pairs[j].Player1, pairs[j].Player2)
+	})
+	return pairs
+}
+
+// HeadToHeadSlug names the page of two players, "<a>-vs-<b>" with the
+// lower slug first.
+func (g *Generator) HeadToHeadSlug(player1, player2 string) string {
+	a, b := g.slugs.Slug(player1), g.slugs.Slug(player2)
+	if b < a {
+		a, b = b, a
+	}
+	return a + "-vs-" + b
+}
+
+func (g *Generator) buildH2HData(h HeadToHead) H2HData {
+	data := H2HData{
+		Player1:   g.playerLink(h.Player1, "../players/"),
+		Player2:   g.playerLink(h.Player2, "../players/"),
+		Timestamp: time.Now().Format("January 2, 2006 15:04"),
+	}
+	for _, m := range h.Sets {
+		row := H2HSetRow{
+			Date:             m.Date.Format("Jan 2, 2006"),
+			TournamentID:     m.TournamentID,
+			TournamentTitle:  tournamentTitle(m.TournamentID, m.TournamentName),
+			Round:            m.Round,
+			Player1Wins:      m.Player1Wins,
+			Player2Wins:      m.Player2Wins,
+			Player1ELOBefore: m.Player1ELOBefore,
+			Player2ELOBefore: m.Player2ELOBefore,
+			Gap:              m.Player1ELOBefore - m.Player2ELOBefore,
+		}
+		switch {
+		case m.Player1Wins > m.Player2Wins:
+			row.Winner = 1
+			data.Player1Sets++
+		case m.Player2Wins > m.Player1Wins:
+			row.Winner = 2
+			data.Player2Sets++
+		}
+		data.Player1Games += m.Player1Wins
+		data.Player2Games += m.Player2Wins
+		data.Sets = append(data.Sets, row)
+	}
+	data.GapChartHTML = template.HTML(generateGapChart(h.Player1, h.Player2, data.Sets))
+	return data
+}
+
+func (g *Generator) renderH2H(h HeadToHead) ([]byte, error) {
+	data := g.buildH2HData(h)
+	return executeTemplate("templates/h2h.tmpl", data)
+}
+
+// generateGapChart plots Player1's rating lead going into each set, with
+// the zero line marking even ratings.
+func generateGapChart(player1, player2 string, sets []H2HSetRow) string {
+	if len(sets) == 0 {
+		return "<p>No sets played</p>"
+	}
+
+	width := 800
+	height := 300
+	padding := 50
+
+	chartWidth := width - 2*padding
+	chartHeight := height - 2*padding
+
+	// Keep zero in the middle so leads for either player read the same way
+	maxGap := 50
+	for _, s := range sets {
+		if s.Gap > maxGap {
+			maxGap = s.Gap
+		}
+		if -s.Gap > maxGap {
+			maxGap = -s.Gap
+		}
+	}
+	maxGap += maxGap / 10
+
+	steps := len(sets) - 1
+	if steps == 0 {
+		steps = 1
+	}
+	y := func(gap int) int {
+		return height/2 - gap*chartHeight/(2*maxGap)
+	}
+
+	points := ""
+	dots := ""
+	for i, s := range sets {
+		x := padding + i*chartWidth/steps
+		if i > 0 {
+			points += " "
+		}
+		points += fmt.Sprintf("%d,%d", x, y(s.Gap))
+		color := "#fbbf24"
+		if s.Winner == 1 {
+			color = "#4ade80"
+		} else if s.Winner == 2 {
+			color = "#f87171"
+		}
+		dots += fmt.Sprintf(`<circle cx="%d" cy="%d" r="5" fill="%s"><title>%s: %+d</title></circle>`,
+			x, y(s.Gap), color, template.HTMLEscapeString(s.Date), s.Gap)
+	}
+
+	return fmt.Sprintf(`<svg viewBox="0 0 %d %d" style="width:100%%;height:100%%;">
+		<rect x="0" y="0" width="%d" height="%d" fill="rgba(255,255,255,0.02)" rx="8" />
+		<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="rgba(255,255,255,0.2)" stroke-width="1" stroke-dasharray="4" />
+		<text x="%d" y="%d" text-anchor="end" fill="#888" font-size="12">+%d</text>
+		<text x="%d" y="%d" text-anchor="end" fill="#888" font-size="12">0</text>
+		<text x="%d" y="%d" text-anchor="end" fill="#888" font-size="12">-%d</text>
+		<polyline points="%s" fill="none" stroke="#667eea" stroke-width="3" stroke-linecap="round" stroke-linejoin="round" />
+		%s
+		<text x="%d" y="%d" text-anchor="middle" fill="#667eea" font-size="14" font-weight="600">%s's rating lead over %s before each set</text>
+	</svg>`,
+		width, height,
+		width, height,
+		padding, height/2, width-padding, height/2,
+		padding-10, y(maxGap)+4, maxGap,
+		padding-10, height/2+4,
+		padding-10, y(-maxGap)+4, maxGap,
+		points,
+		dots,
+		width/2, padding-25, template.HTMLEscapeString(player1), template.HTMLEscapeString(player2),
+	)
+}
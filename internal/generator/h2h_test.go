@@ -0,0 +1,56 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+func TestHeadToHeads(t *testing.T) {
+	g := New("Rankings", "")
+	date := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	matches := []storage.TournamentMatch{
+		{TournamentID: 1, Date: date, Round: 1, Player1: "Zed", Player2: "Amy", Player1Wins: 2, Player2Wins: 1,
+			Player1ELOBefore: 1550, Player2ELOBefore: 1500},
+		{TournamentID: 1, Date: date, Round: 2, Player1: "Amy", Player2: "Bob", Player1Wins: 2, Player2Wins: 0},
+		{TournamentID: 2, Date: date.AddDate(0, 0, 7), Round: 1, Player1: "Amy", Player2: "Zed", Player1Wins: 2, Player2Wins: 0,
+			Player1ELOBefore: 1490, Player2ELOBefore: 1560},
+	}
+
+	pairs := g.HeadToHeads(matches)
+	if len(pairs) != 2 {
+		t.Fatalf("expected 2 pairs, got %d", len(pairs))
+	}
+	if g.HeadToHeadSlug(pairs[0].Player1, pairs[0].Player2) != "amy-vs-bob" {
+		t.Errorf("expected pairs sorted by page name, got %s vs %s first", pairs[0].Player1, pairs[0].Player2)
+	}
+	if g.HeadToHeadSlug("Zed", "Amy") != "amy-vs-zed" {
+		t.Errorf("expected the lower slug first, got %s", g.HeadToHeadSlug("Zed", "Amy"))
+	}
+
+	amyZed := pairs[1]
+	if amyZed.Player1 != "Amy" || len(amyZed.Sets) != 2 {
+		t.Fatalf("expected Amy on the left of both sets, got %+v", amyZed)
+	}
+	if first := amyZed.Sets[0]; first.Player1Wins != 1 || first.Player2Wins != 2 || first.Player1ELOBefore != 1500 {
+		t.Errorf("expected the first set to be swapped around, got %+v", first)
+	}
+
+	data := g.buildH2HData(amyZed)
+	if data.Player1Sets != 1 || data.Player2Sets != 1 || data.Player1Games != 3 || data.Player2Games != 2 {
+		t.Errorf("unexpected totals: %+v", data)
+	}
+	if data.Sets[0].Gap != -50 || data.Sets[1].Gap != -70 || data.Sets[1].Winner != 1 {
+		t.Errorf("unexpected sets: %+v", data.Sets)
+	}
+
+	page, err := g.RenderHeadToHeadPage(amyZed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), `href="../tournaments/2.html"`) {
+		t.Error("expected sets to link their tournament")
+	}
+}
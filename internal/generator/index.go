@@ -1,32 +1,65 @@
 package generator
 
 import (
+	"fmt"
+	"path/filepath"
 	"time"
 
 	"github.com/melee-elo-ranking/internal/storage"
 )
 
 // IndexData is the data passed to the index template.
+// It is also the data of api/rankings.json.
 type IndexData struct {
-	Title     string
-	Subtitle  string
-	Timestamp string
-	Rankings  []IndexRankingRow
+	Title     string `json:"title"`
+	Subtitle  string `json:"subtitle"`
+	Timestamp string `json:"-"`
+	// Root leads from the page to the site root
+	Root         string            `json:"-"`
+	Nav          []NavLink         `json:"-"`
+	Leaderboards []NavGroup        `json:"-"`
+	Rankings     []IndexRankingRow `json:"rankings"`
+}
+
+// NavLink is a link to another ranking page, relative to the current one.
+type NavLink struct {
+	Title  string
+	Href   string
+	Active bool
 }
 
 // IndexRankingRow is one row in the rankings table.
 type IndexRankingRow struct {
-	Rank          int
-	DisplayName   string
-	CurrentELO    int
-	MatchesPlayed int
-	Wins          int
-	Losses        int
-	WinRate       float64
-	WinRateClass  string
+	Rank          int     `json:"rank"`
+	DisplayName   string  `json:"display_name"`
+	Slug          string  `json:"slug"`
+	CurrentELO    int     `json:"elo"`
+	MatchesPlayed int     `json:"matches_played"`
+	Wins          int     `json:"wins"`
+	Losses        int     `json:"losses"`
+	WinRate       float64 `json:"win_rate"`
+	WinRateClass  string  `json:"-"`
+	// Movement since the previous ranking snapshot. New players were not
+	// ranked in it.
+	RankChange    int    `json:"rank_change"`
+	ELOChange     int    `json:"elo_change"`
+	New           bool   `json:"new"`
+	Movement      string `json:"-"`
+	MovementClass string `json:"-"`
 }
 
 func (g *Generator) buildIndexData(rankings []storage.Ranking) IndexData {
+	return IndexData{
+		Title:     g.title,
+		Subtitle:  g.description,
+		Timestamp: time.Now().Format("January 2, 2006 15:04"),
+		Rankings:  g.indexRows(rankings, g.previousRanking != nil),
+	}
+}
+
+// indexRows builds the rankings table, with the movement since the previous
+// ranking snapshot if movement is set.
+func (g *Generator) indexRows(rankings []storage.Ranking, movement bool) []IndexRankingRow {
 	rows := make([]IndexRankingRow, 0, len(rankings))
 	for _, r := range rankings {
 		winRateClass := "neutral"
@@ -38,6 +71,7 @@ func (g *Generator) buildIndexData(rankings []storage.Ranking) IndexData {
 		rows = append(rows, IndexRankingRow{
 			Rank:          r.Rank,
 			DisplayName:   r.DisplayName,
+			Slug:          g.slugs.Slug(r.DisplayName),
 			CurrentELO:    r.CurrentELO,
 			MatchesPlayed: r.MatchesPlayed,
 			Wins:          r.Wins,
@@ -45,16 +79,58 @@ func (g *Generator) buildIndexData(rankings []storage.Ranking) IndexData {
 			WinRate:       r.WinRate,
 			WinRateClass:  winRateClass,
 		})
+		if movement {
+			g.setMovement(&rows[len(rows)-1], r)
+		}
 	}
-	return IndexData{
-		Title:     g.title,
-		Subtitle:  g.description,
-		Timestamp: time.Now().Format("January 2, 2006 15:04"),
-		Rankings:  rows,
+	return rows
+}
+
+// setMovement compares a row with the player's standing in the previous
+// ranking snapshot.
+func (g *Generator) setMovement(row *IndexRankingRow, r storage.Ranking) {
+	previous, ok := g.previousRanking[r.PlayerID]
+	if !ok {
+		row.New = true
+		row.Movement = "NEW"
+		row.MovementClass = "new"
+		return
+	}
+	row.RankChange = previous.Rank - r.Rank
+	row.ELOChange = r.CurrentELO - previous.ELO
+	if row.RankChange > 0 {
+		row.Movement = fmt.Sprintf("▲%d", row.RankChange)
+		row.MovementClass = "up"
+	} else if row.RankChange < 0 {
+		row.Movement = fmt.Sprintf("▼%d", -row.RankChange)
+		row.MovementClass = "down"
+	}
+}
+
+// buildNav links every profile page from the page written to outputPath.
+func (g *Generator) buildNav(outputPath string) []NavLink {
+	if len(g.profiles) < 2 {
+		return nil
+	}
+	dir := filepath.Dir(outputPath)
+	nav := make([]NavLink, 0, len(g.profiles))
+	for _, p := range g.profiles {
+		href, err := filepath.Rel(dir, p.Output)
+		if err != nil {
+			href = p.Output
+		}
+		nav = append(nav, NavLink{
+			Title:  p.Title,
+			Href:   filepath.ToSlash(href),
+			Active: filepath.Clean(p.Output) == filepath.Clean(outputPath),
+		})
 	}
+	return nav
 }
 
-func (g *Generator) renderIndex(rankings []storage.Ranking) ([]byte, error) {
+func (g *Generator) renderIndex(rankings []storage.Ranking, outputPath string) ([]byte, error) {
 	data := g.buildIndexData(rankings)
+	data.Nav = g.buildNav(outputPath)
+	data.Leaderboards = g.leaderboardNav("", "index.html")
 	return executeTemplate("templates/index.tmpl", data)
 }
@@ -0,0 +1,50 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+func TestIndexMovement(t *testing.T) {
+	rankings := []storage.Ranking{
+		{PlayerID: 2, Rank: 1, DisplayName: "Bob", CurrentELO: 1560},
+		{PlayerID: 1, Rank: 2, DisplayName: "Alice", CurrentELO: 1540},
+		{PlayerID: 3, Rank: 3, DisplayName: "Carol", CurrentELO: 1500},
+	}
+
+	g := New("Rankings", "")
+	if rows := g.buildIndexData(rankings).Rankings; rows[2].New || rows[0].Movement != "" {
+		t.Errorf("expected no movement without a previous snapshot, got %+v", rows)
+	}
+
+	g.SetPreviousRanking([]storage.SnapshotEntry{
+		{PlayerID: 1, Rank: 1, ELO: 1550},
+		{PlayerID: 2, Rank: 2, ELO: 1530},
+	})
+	rows := g.buildIndexData(rankings).Rankings
+	if rows[0].RankChange != 1 || rows[0].ELOChange != 30 || rows[0].Movement != "▲1" || rows[0].MovementClass != "up" {
+		t.Errorf("unexpected movement for Bob: %+v", rows[0])
+	}
+	if rows[1].RankChange != -1 || rows[1].ELOChange != -10 || rows[1].Movement != "▼1" {
+		t.Errorf("unexpected movement for Alice: %+v", rows[1])
+	}
+	if !rows[2].New || rows[2].Movement != "NEW" {
+		t.Errorf("expected Carol to be new, got %+v", rows[2])
+	}
+
+	page, err := g.RenderIndex(rankings, "index.html")
+	if err != nil {
+		t.Fatalf("RenderIndex failed: %v", err)
+	}
+	for _, want := range []string{
+		`<span class="movement up">▲1</span>`,
+		`<span class="movement new">NEW</span>`,
+		`<span class="elo-change negative">-10</span>`,
+	} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("expected index to contain %s", want)
+		}
+	}
+}
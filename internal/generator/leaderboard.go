@@ -0,0 +1,123 @@
+package generator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+// NavGroup is a titled row of links in the leaderboard navigation.
+type NavGroup struct {
+	Title string
+	Links []NavLink
+}
+
+// ClubsData is the data passed to the club table template.
+type ClubsData struct {
+	Title        string
+	Timestamp    string
+	TopMembers   int
+	Leaderboards []NavGroup
+	Clubs        []ClubRow
+}
+
+// ClubRow is one club in the club table.
+type ClubRow struct {
+	Rank       int
+	Name       string
+	Href       string
+	Members    int
+	AverageELO int
+}
+
+// clubTablePath is where the club table is written, relative to the site
+// root.
+const clubTablePath = "clubs/index.html"
+
+// LeaderboardPath returns where the leaderboard of a region or club is
+// written, relative to the site root.
+func (g *Generator) LeaderboardPath(group storage.PlayerGroup, name string) string {
+	if group == storage.GroupTeam {
+		return "clubs/" + g.clubSlugs.Slug(name) + ".html"
+	}
+	return "regions/" + g.regionSlugs.Slug(name) + ".html"
+}
+
+// LeaderboardName returns the region or club whose leaderboard has the
+// given slug.
+func (g *Generator) LeaderboardName(group storage.PlayerGroup, slug string) (string, bool) {
+	if group == storage.GroupTeam {
+		return g.clubSlugs.Name(slug)
+	}
+	return g.regionSlugs.Name(slug)
+}
+
+// leaderboardNav links every leaderboard from a page that reaches the site
+// root through root. active is the path of the current page relative to the
+// root. There is no navigation without regions or clubs.
+func (g *Generator) leaderboardNav(root, active string) []NavGroup {
+	if len(g.regions) == 0 && len(g.clubs) == 0 {
+		return nil
+	}
+	link := func(title, path string) NavLink {
+		return NavLink{Title: title, Href: root + path, Active: path == active}
+	}
+
+	overall := NavGroup{Title: "Leaderboards", Links: []NavLink{link("Everyone", "index.html")}}
+	if len(g.clubs) > 0 {
+		overall.Links = append(overall.Links, link("Club table", clubTablePath))
+	}
+	groups := []NavGroup{overall}
+	if len(g.regions) > 0 {
+		regions := NavGroup{Title: "Regions"}
+		for _, name := range g.regions {
+			regions.Links = append(regions.Links, link(name, g.LeaderboardPath(storage.GroupRegion, name)))
+		}
+		groups = append(groups, regions)
+	}
+	if len(g.clubs) > 0 {
+		clubs := NavGroup{Title: "Clubs"}
+		for _, name := range g.clubs {
+			clubs.Links = append(clubs.Links, link(name, g.LeaderboardPath(storage.GroupTeam, name)))
+		}
+		groups = append(groups, clubs)
+	}
+	return groups
+}
+
+func (g *Generator) renderLeaderboard(group storage.PlayerGroup, name string, rankings []storage.Ranking) ([]byte, error) {
+	path := g.LeaderboardPath(group, name)
+	data := IndexData{
+		Title:        name,
+		Subtitle:     fmt.Sprintf("Players from %s, ranked among themselves", name),
+		Timestamp:    time.Now().Format("January 2, 2006 15:04"),
+		Root:         "../",
+		Leaderboards: g.leaderboardNav("../", path),
+		// Movement is against the overall ranking, so it is left out
+		Rankings: g.indexRows(rankings, false),
+	}
+	if group == storage.GroupTeam {
+		data.Subtitle = fmt.Sprintf("Members of %s, ranked among themselves", name)
+	}
+	return executeTemplate("templates/index.tmpl", data)
+}
+
+func (g *Generator) renderClubTable(clubs []storage.ClubRanking, topMembers int) ([]byte, error) {
+	data := ClubsData{
+		Title:        g.title,
+		Timestamp:    time.Now().Format("January 2, 2006 15:04"),
+		TopMembers:   topMembers,
+		Leaderboards: g.leaderboardNav("../", clubTablePath),
+	}
+	for _, c := range clubs {
+		data.Clubs = append(data.Clubs, ClubRow{
+			Rank:       c.Rank,
+			Name:       c.Team,
+			Href:       "../" + g.LeaderboardPath(storage.GroupTeam, c.Team),
+			Members:    c.Members,
+			AverageELO: c.AverageELO,
+		})
+	}
+	return executeTemplate("templates/clubs.tmpl", data)
+}
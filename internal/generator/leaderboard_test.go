@@ -0,0 +1,86 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+func TestLeaderboards(t *testing.T) {
+	g := New("Rankings", "")
+	if nav := g.leaderboardNav("", "index.html"); nav != nil {
+		t.Errorf("expected no navigation without regions or clubs, got %+v", nav)
+	}
+
+	g.SetLeaderboards([]string{"Île-de-France", "Lyon"}, []string{"Les Bleus"})
+	if path := g.LeaderboardPath(storage.GroupRegion, "Île-de-France"); path != "regions/ile-de-france.html" {
+		t.Errorf("unexpected region path %q", path)
+	}
+	if name, ok := g.LeaderboardName(storage.GroupTeam, "les-bleus"); !ok || name != "Les Bleus" {
+		t.Errorf("expected to find the club by its slug, got %q", name)
+	}
+
+	g.SetPreviousRanking([]storage.SnapshotEntry{{PlayerID: 1, Rank: 5, ELO: 1600}})
+	page, err := g.RenderLeaderboard(storage.GroupRegion, "Lyon", []storage.Ranking{
+		{PlayerID: 1, Rank: 1, DisplayName: "Alice", CurrentELO: 1620, MatchesPlayed: 10, Wins: 7, Losses: 3, WinRate: 70},
+	})
+	if err != nil {
+		t.Fatalf("RenderLeaderboard failed: %v", err)
+	}
+	html := string(page)
+	for _, want := range []string{
+		"Players from Lyon",
+		`href="../players/alice.html"`,
+		`<a href="../regions/lyon.html" class="active">Lyon</a>`,
+		`<a href="../clubs/les-bleus.html">Les Bleus</a>`,
+		`href="../index.html">Everyone`,
+		`href="../clubs/index.html">Club table`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected leaderboard to contain %q", want)
+		}
+	}
+	// The overall rank change means nothing within a region
+	if strings.Contains(html, "▲") {
+		t.Error("expected no movement on leaderboards")
+	}
+
+	page, err = g.RenderClubTable([]storage.ClubRanking{{Rank: 1, Team: "Les Bleus", Members: 4, AverageELO: 1650}}, 3)
+	if err != nil {
+		t.Fatalf("RenderClubTable failed: %v", err)
+	}
+	html = string(page)
+	for _, want := range []string{"Top 3 Average", `<a href="../clubs/les-bleus.html">Les Bleus</a>`, "<td>1650</td>", `class="active">Club table`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected club table to contain %q", want)
+		}
+	}
+
+	page, err = g.RenderIndex(nil, "index.html")
+	if err != nil {
+		t.Fatalf("RenderIndex failed: %v", err)
+	}
+	if !strings.Contains(string(page), `<a href="regions/lyon.html">Lyon</a>`) {
+		t.Error("expected the index to link the leaderboards")
+	}
+}
+
+func TestClubNamedIndex(t *testing.T) {
+	g := New("Rankings", "")
+	g.SetLeaderboards(nil, []string{"Index"})
+
+	if path := g.LeaderboardPath(storage.GroupTeam, "Index"); path != "clubs/index-2.html" {
+		t.Errorf("expected the club to keep clear of the club table, got %q", path)
+	}
+	if _, ok := g.LeaderboardName(storage.GroupTeam, "index"); ok {
+		t.Error("expected index not to resolve to a club")
+	}
+	nav := g.leaderboardNav("../", clubTablePath)
+	if table := nav[0].Links[1]; table.Href != "../clubs/index.html" || !table.Active {
+		t.Errorf("expected the club table link to be active, got %+v", table)
+	}
+	if club := nav[1].Links[0]; club.Href != "../clubs/index-2.html" || club.Active {
+		t.Errorf("expected the club's link to stay inactive, got %+v", club)
+	}
+}
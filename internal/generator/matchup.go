@@ -2,31 +2,131 @@ package generator
 
 import (
 	"fmt"
-	"html/template"
 	"time"
 
 	"github.com/melee-elo-ranking/internal/storage"
 )
 
 // MatchupData is the data passed to the matchup template.
+// It is also the data of api/matchups.json.
 type MatchupData struct {
-	Timestamp         string
-	MatrixHeaderHTML  template.HTML
-	MatrixBodyHTML    template.HTML
+	Timestamp string         `json:"-"`
+	Columns   []MatrixPlayer `json:"-"`
+	Rows      []MatrixRow    `json:"-"`
+	MinSets   int            `json:"min_sets"`
+	Players   []string       `json:"players"`
+	Matchups  []MatchupRow   `json:"matchups"`
+}
+
+// MatchupRow is one player's record against one opponent.
+type MatchupRow struct {
+	Player1           string  `json:"player1"`
+	Player2           string  `json:"player2"`
+	Player1Sets       int     `json:"player1_sets"`
+	Player2Sets       int     `json:"player2_sets"`
+	SetsPlayed        int     `json:"sets_played"`
+	Player1SetWinRate float64 `json:"player1_set_win_rate"`
+	Player1Wins       int     `json:"player1_wins"`
+	Player2Wins       int     `json:"player2_wins"`
+	GamesPlayed       int     `json:"games_played"`
+	Player1WinRate    float64 `json:"player1_win_rate"`
+}
+
+// MatrixPlayer is a row or column of the matrix. Index is the player's
+// position in the rankings, which the page uses to filter and re-sort.
+type MatrixPlayer struct {
+	Index     int
+	Name      string
+	ShortName string
+}
+
+// MatrixRow is one player's records against every column player.
+type MatrixRow struct {
+	Player MatrixPlayer
+	Cells  []MatrixCell
+}
+
+// MatrixCell is one record in the matrix. Cells on the diagonal and between
+// players who never met have no Record.
+type MatrixCell struct {
+	Column int
+	Record *MatchupRow
+	Href   string
+	// Small marks records of fewer sets than the threshold
+	Small bool
+}
+
+// SetsClass colours the cell by set win rate.
+func (c MatrixCell) SetsClass() string {
+	return winRateClass(c.Record.Player1SetWinRate)
+}
+
+// GamesClass colours the cell by game win rate.
+func (c MatrixCell) GamesClass() string {
+	return winRateClass(c.Record.Player1WinRate)
+}
+
+// Detail is the tooltip of the cell.
+func (c MatrixCell) Detail() string {
+	m := c.Record
+	return fmt.Sprintf("%s vs %s: %d-%d in sets (%.0f%%), %d-%d in games (%.0f%%)",
+		m.Player1, m.Player2, m.Player1Sets, m.Player2Sets, m.Player1SetWinRate,
+		m.Player1Wins, m.Player2Wins, m.Player1WinRate)
 }
 
 func (g *Generator) buildMatchupData(matchups []storage.Matchup, players []string) MatchupData {
+	// Matchups are stored once per pair; the matrix needs both sides
 	matchupMap := make(map[string]map[string]storage.Matchup)
 	for _, m := range matchups {
-		if matchupMap[m.Player1] == nil {
-			matchupMap[m.Player1] = make(map[string]storage.Matchup)
+		for _, side := range []storage.Matchup{m, m.Reverse()} {
+			if matchupMap[side.Player1] == nil {
+				matchupMap[side.Player1] = make(map[string]storage.Matchup)
+			}
+			matchupMap[side.Player1][side.Player2] = side
+		}
+	}
+
+	columns := make([]MatrixPlayer, len(players))
+	for i, player := range players {
+		columns[i] = MatrixPlayer{Index: i, Name: player, ShortName: shortName(player)}
+	}
+
+	// Records only cover the listed players, in the order of the matrix.
+	records := make([]MatchupRow, 0, len(matchups))
+	rows := make([]MatrixRow, len(players))
+	for i, player1 := range players {
+		rows[i] = MatrixRow{Player: columns[i], Cells: make([]MatrixCell, len(players))}
+		for j, player2 := range players {
+			rows[i].Cells[j].Column = j
+			m, found := matchupMap[player1][player2]
+			if player1 == player2 || !found || m.GamesPlayed == 0 {
+				continue
+			}
+			record := MatchupRow{
+				Player1:           m.Player1,
+				Player2:           m.Player2,
+				Player1Sets:       m.Player1Sets,
+				Player2Sets:       m.Player2Sets,
+				SetsPlayed:        m.SetsPlayed,
+				Player1SetWinRate: m.Player1SetWinRate,
+				Player1Wins:       m.Player1Wins,
+				Player2Wins:       m.Player2Wins,
+				GamesPlayed:       m.GamesPlayed,
+				Player1WinRate:    m.Player1WinRate,
+			}
+			records = append(records, record)
+			rows[i].Cells[j].Record = &record
+			rows[i].Cells[j].Href = g.h2hHref(player1, player2)
+			rows[i].Cells[j].Small = m.SetsPlayed < g.matchupMinSets
 		}
-		matchupMap[m.Player1][m.Player2] = m
 	}
 	return MatchupData{
-		Timestamp:        time.Now().Format("January 2, 2006 15:04"),
-		MatrixHeaderHTML: template.HTML(generateMatrixHeader(players)),
-		MatrixBodyHTML:   template.HTML(generateMatrixBody(players, matchupMap)),
+		Timestamp: time.Now().Format("January 2, 2006 15:04"),
+		Columns:   columns,
+		Rows:      rows,
+		MinSets:   g.matchupMinSets,
+		Players:   players,
+		Matchups:  records,
 	}
 }
 
@@ -35,47 +135,26 @@ func (g *Generator) renderMatchup(matchups []storage.Matchup, players []string)
 	return executeTemplate("templates/matchup.tmpl", data)
 }
 
-func generateMatrixHeader(players []string) string {
-	header := ""
-	for _, player := range players {
-		shortName := player
-		if len(shortName) > 8 {
-			shortName = shortName[:8] + "."
-		}
-		header += fmt.Sprintf(`<th title="%s">%s</th>`, template.HTMLEscapeString(player), template.HTMLEscapeString(shortName))
+// shortName abbreviates a column header to eight characters.
+func shortName(name string) string {
+	runes := []rune(name)
+	if len(runes) > 8 {
+		return string(runes[:8]) + "."
 	}
-	return header
+	return name
 }
 
-func generateMatrixBody(players []string, matchupMap map[string]map[string]storage.Matchup) string {
-	body := ""
-	for _, player1 := range players {
-		body += "<tr>"
-		body += fmt.Sprintf(`<th class="row-header" title="%s">%s</th>`, template.HTMLEscapeString(player1), template.HTMLEscapeString(player1))
-		for _, player2 := range players {
-			if player1 == player2 {
-				body += `<td class="empty">-</td>`
-			} else {
-				m, found := matchupMap[player1][player2]
-				if !found || m.GamesPlayed == 0 {
-					body += `<td class="empty">-</td>`
-				} else {
-					cellClass := "cell-neutral"
-					if m.Player1WinRate >= 60 {
-						cellClass = "cell-positive"
-					} else if m.Player1WinRate < 40 {
-						cellClass = "cell-negative"
-					}
-					body += fmt.Sprintf(`<td class="cell %s" data-games="%d" data-detail="%s vs %s: %d-%d">%.0f%%</td>`,
-						cellClass,
-						m.GamesPlayed,
-						template.HTMLEscapeString(player1), template.HTMLEscapeString(player2),
-						m.Player1Wins, m.Player2Wins,
-						m.Player1WinRate)
-				}
-			}
-		}
-		body += "</tr>"
+// h2hHref links the head-to-head page of two players from the matrix.
+func (g *Generator) h2hHref(player1, player2 string) string {
+	return "h2h/" + g.HeadToHeadSlug(player1, player2) + ".html"
+}
+
+// winRateClass colours a win rate the way the rankings do.
+func winRateClass(rate float64) string {
+	if rate >= 60 {
+		return "cell-positive"
+	} else if rate < 40 {
+		return "cell-negative"
 	}
-	return body
+	return "cell-neutral"
 }
@@ -0,0 +1,75 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+func TestMatchupMatrixUsesSets(t *testing.T) {
+	g := New("Rankings", "")
+	g.SetMatchupMinSets(5)
+
+	// Alice took one set 2-0 and lost three 1-2: ahead in games, behind in sets
+	matchups := []storage.Matchup{{
+		Player1: "Alice", Player2: "Bob",
+		Player1Sets: 1, Player2Sets: 3, SetsPlayed: 4, Player1SetWinRate: 25,
+		Player1Wins: 5, Player2Wins: 6, GamesPlayed: 11, Player1WinRate: 45.5,
+	}}
+	data := g.buildMatchupData(matchups, []string{"Bob", "Alice"})
+	page, err := g.RenderMatchupMatrix(matchups, []string{"Bob", "Alice"})
+	if err != nil {
+		t.Fatalf("RenderMatchupMatrix failed: %v", err)
+	}
+	body := string(page)
+
+	for _, want := range []string{
+		`<span class="by-sets cell-negative">1-3</span><span class="by-games cell-neutral">5-6</span>`,
+		`<span class="by-sets cell-positive">3-1</span><span class="by-games cell-neutral">6-5</span>`,
+		`class="cell cell-small" data-player="0" data-sets="4"`,
+		`href="h2h/alice-vs-bob.html"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected matrix to contain %s", want)
+		}
+	}
+
+	if data.MinSets != 5 || len(data.Matchups) != 2 || data.Matchups[0].Player1 != "Bob" || data.Matchups[0].Player1Sets != 3 {
+		t.Errorf("unexpected matchup data: %+v", data)
+	}
+}
+
+func TestMatchupMatrixCells(t *testing.T) {
+	g := New("Rankings", "")
+
+	matchups := []storage.Matchup{{
+		Player1: "Alice", Player2: "Bob",
+		Player1Sets: 2, Player2Sets: 1, SetsPlayed: 3, Player1SetWinRate: 66.7,
+		Player1Wins: 5, Player2Wins: 4, GamesPlayed: 9, Player1WinRate: 55.6,
+	}}
+	data := g.buildMatchupData(matchups, []string{"Bob", "Alice", "Christopher"})
+
+	if len(data.Columns) != 3 || data.Columns[2].Index != 2 || data.Columns[2].ShortName != "Christop." {
+		t.Errorf("unexpected columns: %+v", data.Columns)
+	}
+	if len(data.Rows) != 3 || len(data.Rows[0].Cells) != 3 {
+		t.Fatalf("expected a 3x3 matrix, got %+v", data.Rows)
+	}
+
+	// Bob's row: nothing against himself or Christopher, 1-2 against Alice
+	bob := data.Rows[0]
+	if bob.Cells[0].Record != nil || bob.Cells[2].Record != nil {
+		t.Errorf("expected empty cells on the diagonal and between strangers: %+v", bob.Cells)
+	}
+	cell := bob.Cells[1]
+	if cell.Column != 1 || cell.Record == nil || cell.Record.Player1Sets != 1 || cell.Record.Player2Sets != 2 {
+		t.Fatalf("unexpected Bob vs Alice cell: %+v", cell)
+	}
+	if cell.SetsClass() != "cell-negative" || cell.GamesClass() != "cell-neutral" || cell.Small {
+		t.Errorf("unexpected cell classes: %s %s small=%v", cell.SetsClass(), cell.GamesClass(), cell.Small)
+	}
+	if got := data.Rows[1].Cells[0].Record; got == nil || got.Player1 != "Alice" || got.Player1Sets != 2 {
+		t.Errorf("expected Alice's row to hold her side of the record, got %+v", got)
+	}
+}
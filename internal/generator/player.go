@@ -1,39 +1,78 @@
 package generator
 
 import (
-	"fmt"
 	"html/template"
+	"strings"
 	"time"
 
 	"github.com/melee-elo-ranking/internal/storage"
 )
 
 // PlayerData is the data passed to the player template.
+// It is also the data of api/players/<name>.json.
 type PlayerData struct {
-	PlayerName    string
-	Timestamp     string
-	CurrentELO    int
-	Rank          int
-	MatchesPlayed int
-	Wins          int
-	Losses        int
-	WinRate       float64
-	WinRateClass  string
-	ELOChartHTML  template.HTML
-	Matches       []PlayerMatchRow
+	PlayerName    string           `json:"display_name"`
+	Slug          string           `json:"slug"`
+	Timestamp     string           `json:"-"`
+	Region        string           `json:"region,omitempty"`
+	Team          string           `json:"team,omitempty"`
+	Main          string           `json:"main,omitempty"`
+	Links         []string         `json:"links,omitempty"`
+	ProfileLine   string           `json:"-"`
+	CurrentELO    int              `json:"elo"`
+	Rank          int              `json:"rank"`
+	MatchesPlayed int              `json:"matches_played"`
+	Wins          int              `json:"wins"`
+	Losses        int              `json:"losses"`
+	WinRate       float64          `json:"win_rate"`
+	WinRateClass  string           `json:"-"`
+	ELOChartHTML  template.HTML    `json:"-"`
+	ChartOverlays []ChartOverlay   `json:"-"`
+	RankChartHTML template.HTML    `json:"-"`
+	RankHistory   []RankHistoryRow `json:"rank_history"`
+	Tournaments   []PerformanceRow `json:"tournaments"`
+	Matches       []PlayerMatchRow `json:"matches"`
+}
+
+// RankHistoryRow is the player's standing in one ranking snapshot.
+type RankHistoryRow struct {
+	Date string `json:"date"`
+	Rank int    `json:"rank"`
+	ELO  int    `json:"elo"`
+}
+
+// PerformanceRow is the player's performance at one rated tournament.
+type PerformanceRow struct {
+	Date             string `json:"-"`
+	ISODate          string `json:"date"`
+	TournamentID     int    `json:"tournament_id"`
+	TournamentName   string `json:"tournament"`
+	TournamentTitle  string `json:"-"`
+	Wins             int    `json:"wins"`
+	Losses           int    `json:"losses"`
+	Draws            int    `json:"draws"`
+	OpponentAverage  int    `json:"opponent_average"`
+	Performance      int    `json:"performance"`
+	PerformanceClass string `json:"-"`
+	ELOBefore        int    `json:"elo_before"`
+	ELOAfter         int    `json:"elo_after"`
 }
 
 // PlayerMatchRow is one row in the match history table.
 type PlayerMatchRow struct {
-	Date           string
-	Round          int
-	OpponentName   string
-	PlayerWins     int
-	OpponentWins   int
-	Result         string
-	ResultClass    string
-	PlayerELOBefore int
-	PlayerELOAfter  int
+	Date            string `json:"-"`
+	ISODate         string `json:"date"`
+	TournamentID    int    `json:"tournament_id"`
+	TournamentName  string `json:"tournament"`
+	TournamentTitle string `json:"-"`
+	Round           int    `json:"round"`
+	OpponentName    string `json:"opponent"`
+	PlayerWins      int    `json:"player_wins"`
+	OpponentWins    int    `json:"opponent_wins"`
+	Result          string `json:"result"`
+	ResultClass     string `json:"-"`
+	PlayerELOBefore int    `json:"elo_before"`
+	PlayerELOAfter  int    `json:"elo_after"`
 }
 
 func (g *Generator) buildPlayerData(playerName string, matches []storage.PlayerMatch, playerStats storage.Ranking) PlayerData {
@@ -47,12 +86,16 @@ func (g *Generator) buildPlayerData(playerName string, matches []storage.PlayerM
 		}
 		rows = append(rows, PlayerMatchRow{
 			Date:            m.DatePlayed.Format("Jan 2, 2006"),
+			ISODate:         m.DatePlayed.Format("2006-01-02"),
+			TournamentID:    m.TournamentID,
+			TournamentName:  m.TournamentName,
+			TournamentTitle: tournamentTitle(m.TournamentID, m.TournamentName),
 			Round:           m.Round,
 			OpponentName:    m.OpponentName,
-			PlayerWins:     m.PlayerWins,
-			OpponentWins:   m.OpponentWins,
-			Result:         m.Result,
-			ResultClass:    resultClass,
+			PlayerWins:      m.PlayerWins,
+			OpponentWins:    m.OpponentWins,
+			Result:          m.Result,
+			ResultClass:     resultClass,
 			PlayerELOBefore: m.PlayerELOBefore,
 			PlayerELOAfter:  m.PlayerELOAfter,
 		})
@@ -65,9 +108,50 @@ func (g *Generator) buildPlayerData(playerName string, matches []storage.PlayerM
 		winRateClass = "negative"
 	}
 
+	overlays := g.chartOverlays(playerName, matches)
+	snapshots := g.rankHistories[playerStats.PlayerID]
+	rankHistory := make([]RankHistoryRow, len(snapshots))
+	for i, e := range snapshots {
+		rankHistory[i] = RankHistoryRow{Date: e.Date.Format("2006-01-02"), Rank: e.Rank, ELO: e.ELO}
+	}
+	performances := g.performances[playerName]
+	tournaments := make([]PerformanceRow, 0, len(performances))
+	for _, p := range performances {
+		tournaments = append(tournaments, PerformanceRow{
+			Date:             p.Date.Format("Jan 2, 2006"),
+			ISODate:          p.Date.Format("2006-01-02"),
+			TournamentID:     p.TournamentID,
+			TournamentName:   p.TournamentName,
+			TournamentTitle:  tournamentTitle(p.TournamentID, p.TournamentName),
+			Wins:             p.Wins,
+			Losses:           p.Losses,
+			Draws:            p.Draws,
+			OpponentAverage:  p.OpponentAverage,
+			Performance:      p.Performance,
+			PerformanceClass: performanceClass(p.Performance, p.ELOBefore),
+			ELOBefore:        p.ELOBefore,
+			ELOAfter:         p.ELOAfter,
+		})
+	}
+	profile := g.playerProfiles[playerName]
+	var details []string
+	for _, detail := range []string{profile.Region, profile.Team} {
+		if detail != "" {
+			details = append(details, detail)
+		}
+	}
+	if profile.Main != "" {
+		details = append(details, "Main: "+profile.Main)
+	}
 	return PlayerData{
 		PlayerName:    playerName,
+		Slug:          g.slugs.Slug(playerName),
 		Timestamp:     time.Now().Format("January 2, 2006 15:04"),
+		Region:        profile.Region,
+		Team:          profile.Team,
+		Main:          profile.Main,
+		Links:         profile.Links,
+		ProfileLine:   strings.Join(details, " · "),
 		CurrentELO:    playerStats.CurrentELO,
 		Rank:          playerStats.Rank,
 		MatchesPlayed: playerStats.MatchesPlayed,
@@ -75,90 +159,27 @@ func (g *Generator) buildPlayerData(playerName string, matches []storage.PlayerM
 		Losses:        playerStats.Losses,
 		WinRate:       playerStats.WinRate,
 		WinRateClass:  winRateClass,
-		ELOChartHTML:  template.HTML(generateELOChart(matches)),
+		ELOChartHTML:  template.HTML(generateELOChart(matches, overlays)),
+		ChartOverlays: overlays,
+		RankChartHTML: template.HTML(generateRankChart(snapshots)),
+		RankHistory:   rankHistory,
+		Tournaments:   tournaments,
 		Matches:       rows,
 	}
 }
 
+// performanceClass compares a tournament performance with the rating the
+// player came in with, which only catches up over several events.
+func performanceClass(performance, eloBefore int) string {
+	if performance > eloBefore {
+		return "positive"
+	} else if performance < eloBefore {
+		return "negative"
+	}
+	return "neutral"
+}
+
 func (g *Generator) renderPlayer(playerName string, matches []storage.PlayerMatch, playerStats storage.Ranking) ([]byte, error) {
 	data := g.buildPlayerData(playerName, matches, playerStats)
 	return executeTemplate("templates/player.tmpl", data)
 }
-
-func generateELOChart(matches []storage.PlayerMatch) string {
-	if len(matches) == 0 {
-		return "<p>No match data available</p>"
-	}
-
-	width := 800
-	height := 300
-	padding := 50
-
-	chartWidth := width - 2*padding
-	chartHeight := height - 2*padding
-
-	minELO := matches[0].PlayerELOBefore
-	maxELO := matches[0].PlayerELOBefore
-	for _, m := range matches {
-		if m.PlayerELOBefore < minELO {
-			minELO = m.PlayerELOBefore
-		}
-		if m.PlayerELOAfter < minELO {
-			minELO = m.PlayerELOAfter
-		}
-		if m.PlayerELOBefore > maxELO {
-			maxELO = m.PlayerELOBefore
-		}
-		if m.PlayerELOAfter > maxELO {
-			maxELO = m.PlayerELOAfter
-		}
-	}
-
-	eloRange := maxELO - minELO
-	if eloRange == 0 {
-		eloRange = 100
-	}
-	minELO -= eloRange / 10
-	maxELO += eloRange / 10
-	eloRange = maxELO - minELO
-
-	points := ""
-	for i, m := range matches {
-		x := padding + (i * chartWidth / (len(matches) - 1))
-		y := height - padding - ((m.PlayerELOAfter - minELO) * chartHeight / eloRange)
-		if i > 0 {
-			points += " "
-		}
-		points += fmt.Sprintf("%d,%d", x, y)
-	}
-
-	yAxisLabels := ""
-	for i := 0; i <= 5; i++ {
-		value := minELO + (eloRange * i / 5)
-		y := height - padding - (chartHeight * i / 5)
-		yAxisLabels += fmt.Sprintf(`<text x="%d" y="%d" text-anchor="end" fill="#888" font-size="12">%d</text>`, padding-10, int(y)+4, value)
-	}
-
-	return fmt.Sprintf(`<svg viewBox="0 0 %d %d" style="width:100%%;height:100%%;">
-		<defs>
-			<linearGradient id="lineGradient" x1="0%%" y1="0%%" x2="100%%" y2="0%%">
-				<stop offset="0%%" style="stop-color:#667eea" />
-				<stop offset="100%%" style="stop-color:#764ba2" />
-			</linearGradient>
-		</defs>
-		<rect x="0" y="0" width="%d" height="%d" fill="rgba(255,255,255,0.02)" rx="8" />
-		<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="rgba(255,255,255,0.1)" stroke-width="1" />
-		<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="rgba(255,255,255,0.1)" stroke-width="1" />
-		%s
-		<polyline points="%s" fill="none" stroke="url(#lineGradient)" stroke-width="3" stroke-linecap="round" stroke-linejoin="round" />
-		<text x="%d" y="%d" text-anchor="middle" fill="#667eea" font-size="14" font-weight="600">ELO Over Time (by Tournament)</text>
-	</svg>`,
-		width, height,
-		width, height,
-		padding, padding, padding, height-padding,
-		padding, height-padding, width-padding, height-padding,
-		yAxisLabels,
-		points,
-		width/2, padding-15,
-	)
-}
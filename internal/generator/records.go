@@ -0,0 +1,317 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+// RecordsData is the data passed to the records template.
+type RecordsData struct {
+	Title     string
+	Timestamp string
+	Records   []RecordRow
+}
+
+// RecordRow is one record and who holds it. Links point to the tournament
+// or head-to-head page the record was set on.
+type RecordRow struct {
+	Name    string
+	Value   string
+	Players []PlayerLink
+	Links   []PlayerLink
+	Date    string
+}
+
+// playerRecord accumulates one player's records over their rated sets.
+type playerRecord struct {
+	sets        int
+	tournaments map[int]bool
+
+	peak           int
+	peakTournament storage.TournamentMatch
+
+	// bestGain is the largest net rating change over one tournament
+	bestGain           int
+	bestGainTournament storage.TournamentPerformance
+
+	// The current and longest runs of won and of unbeaten sets
+	winStreak, unbeatenStreak streak
+	bestWins, bestUnbeaten    streak
+}
+
+// streak is a run of sets between two dates.
+type streak struct {
+	length     int
+	start, end time.Time
+}
+
+func (s *streak) extend(date time.Time) {
+	if s.length == 0 {
+		s.start = date
+	}
+	s.length++
+	s.end = date
+}
+
+func (s streak) dates() string {
+	if s.start.Equal(s.end) {
+		return s.start.Format("Jan 2, 2006")
+	}
+	return s.start.Format("Jan 2, 2006") + " – " + s.end.Format("Jan 2, 2006")
+}
+
+func (g *Generator) buildRecordsData(matches []storage.TournamentMatch) RecordsData {
+	records := make(map[string]*playerRecord)
+	get := func(name string) *playerRecord {
+		r, ok := records[name]
+		if !ok {
+			r = &playerRecord{tournaments: make(map[int]bool)}
+			records[name] = r
+		}
+		return r
+	}
+
+	var upset *storage.TournamentMatch
+	upsetGap := 0
+	pairs := make(map[[2]string][2]int)
+	for i, m := range matches {
+		// Unrated sets carry no ratings to compare
+		if m.Player1ELOAfter == 0 || m.Player2ELOAfter == 0 {
+			continue
+		}
+		winner := 0
+		if m.Player1Wins > m.Player2Wins {
+			winner = 1
+		} else if m.Player2Wins > m.Player1Wins {
+			winner = 2
+		}
+		sides := []struct {
+			name          string
+			before, after int
+			won, lost     bool
+		}{
+			{m.Player1, m.Player1ELOBefore, m.Player1ELOAfter, winner == 1, winner == 2},
+			{m.Player2, m.Player2ELOBefore, m.Player2ELOAfter, winner == 2, winner == 1},
+		}
+		for _, side := range sides {
+			r := get(side.name)
+			r.sets++
+			r.tournaments[m.TournamentID] = true
+			if side.after > r.peak {
+				r.peak = side.after
+				r.peakTournament = m
+			}
+
+			if side.won {
+				r.winStreak.extend(m.Date)
+			} else {
+				r.winStreak = streak{}
+			}
+			if !side.lost {
+				r.unbeatenStreak.extend(m.Date)
+			} else {
+				r.unbeatenStreak = streak{}
+			}
+			if r.winStreak.length > r.bestWins.length {
+				r.bestWins = r.winStreak
+			}
+			if r.unbeatenStreak.length > r.bestUnbeaten.length {
+				r.bestUnbeaten = r.unbeatenStreak
+			}
+		}
+
+		gap := 0
+		if winner == 1 {
+			gap = m.Player2ELOBefore - m.Player1ELOBefore
+		} else if winner == 2 {
+			gap = m.Player1ELOBefore - m.Player2ELOBefore
+		}
+		if gap > upsetGap {
+			upsetGap = gap
+			upset = &matches[i]
+		}
+
+		// Rivalries are kept from the side of the alphabetically first player
+		key, won, lost := [2]string{m.Player1, m.Player2}, winner == 1, winner == 2
+		if m.Player2 < m.Player1 {
+			key, won, lost = [2]string{m.Player2, m.Player1}, lost, won
+		}
+		sets := pairs[key]
+		if won {
+			sets[0]++
+		} else if lost {
+			sets[1]++
+		}
+		pairs[key] = sets
+	}
+
+	// A gain counts from the first set of a tournament to the last, so a
+	// run that is given back later in the event is not a gain
+	for _, p := range storage.Performances(matches) {
+		r := records[p.Player]
+		if gain := p.ELOAfter - p.ELOBefore; gain > r.bestGain {
+			r.bestGain = gain
+			r.bestGainTournament = p
+		}
+	}
+
+	names := make([]string, 0, len(records))
+	for name := range records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// best returns the player with the highest score, the first by name on a
+	// tie, or "" if nobody scores above zero.
+	best := func(score func(*playerRecord) int) string {
+		holder, top := "", 0
+		for _, name := range names {
+			if s := score(records[name]); s > top {
+				holder, top = name, s
+			}
+		}
+		return holder
+	}
+
+	var rows []RecordRow
+	if name := best(func(r *playerRecord) int { return r.peak }); name != "" {
+		r := records[name]
+		rows = append(rows, RecordRow{
+			Name:    "Highest peak rating",
+			Value:   fmt.Sprintf("%d", r.peak),
+			Players: []PlayerLink{g.playerLink(name, "players/")},
+			Links:   []PlayerLink{g.tournamentLink(r.peakTournament.TournamentID, r.peakTournament.TournamentName)},
+			Date:    r.peakTournament.Date.Format("Jan 2, 2006"),
+		})
+	}
+	if name := best(func(r *playerRecord) int { return r.bestGain }); name != "" {
+		r := records[name]
+		rows = append(rows, RecordRow{
+			Name:    "Biggest tournament gain",
+			Value:   fmt.Sprintf("%+d", r.bestGain),
+			Players: []PlayerLink{g.playerLink(name, "players/")},
+			Links:   []PlayerLink{g.tournamentLink(r.bestGainTournament.TournamentID, r.bestGainTournament.TournamentName)},
+			Date:    r.bestGainTournament.Date.Format("Jan 2, 2006"),
+		})
+	}
+	if upset != nil {
+		winner, loser := upset.Player1, upset.Player2
+		if upset.Player2Wins > upset.Player1Wins {
+			winner, loser = loser, winner
+		}
+		rows = append(rows, RecordRow{
+			Name:    "Biggest upset",
+			Value:   fmt.Sprintf("%d points", upsetGap),
+			Players: []PlayerLink{g.playerLink(winner, "players/"), g.playerLink(loser, "players/")},
+			Links:   []PlayerLink{g.tournamentLink(upset.TournamentID, upset.TournamentName), {Name: "Head-to-head", Href: g.h2hHref(winner, loser)}},
+			Date:    upset.Date.Format("Jan 2, 2006"),
+		})
+	}
+	if name := best(func(r *playerRecord) int { return r.bestWins.length }); name != "" {
+		r := records[name]
+		rows = append(rows, RecordRow{
+			Name:    "Longest win streak",
+			Value:   fmt.Sprintf("%d sets", r.bestWins.length),
+			Players: []PlayerLink{g.playerLink(name, "players/")},
+			Date:    r.bestWins.dates(),
+		})
+	}
+	if name := best(func(r *playerRecord) int { return r.bestUnbeaten.length }); name != "" {
+		r := records[name]
+		rows = append(rows, RecordRow{
+			Name:    "Longest unbeaten streak",
+			Value:   fmt.Sprintf("%d sets", r.bestUnbeaten.length),
+			Players: []PlayerLink{g.playerLink(name, "players/")},
+			Date:    r.bestUnbeaten.dates(),
+		})
+	}
+	if name := best(func(r *playerRecord) int { return r.sets }); name != "" {
+		rows = append(rows, RecordRow{
+			Name:    "Most sets played",
+			Value:   fmt.Sprintf("%d sets", records[name].sets),
+			Players: []PlayerLink{g.playerLink(name, "players/")},
+		})
+	}
+	if name := best(func(r *playerRecord) int { return len(r.tournaments) }); name != "" {
+		rows = append(rows, RecordRow{
+			Name:    "Most tournaments attended",
+			Value:   fmt.Sprintf("%d tournaments", len(records[name].tournaments)),
+			Players: []PlayerLink{g.playerLink(name, "players/")},
+		})
+	}
+	if row, ok := g.lopsidedRivalry(pairs); ok {
+		rows = append(rows, row)
+	}
+
+	return RecordsData{
+		Title:     g.title,
+		Timestamp: time.Now().Format("January 2, 2006 15:04"),
+		Records:   rows,
+	}
+}
+
+// lopsidedRivalry finds the pair with the highest share of decided sets
+// going one way, among pairs that played at least as many sets as the
+// matchup matrix asks for. More sets break a tie.
+func (g *Generator) lopsidedRivalry(pairs map[[2]string][2]int) (RecordRow, bool) {
+	minSets := g.matchupMinSets
+	if minSets < 1 {
+		minSets = 1
+	}
+	keys := make([][2]string, 0, len(pairs))
+	for key := range pairs {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+
+	var bestKey [2]string
+	var bestSets [2]int
+	found := false
+	for _, key := range keys {
+		sets := pairs[key]
+		if sets[1] > sets[0] {
+			key, sets = [2]string{key[1], key[0]}, [2]int{sets[1], sets[0]}
+		}
+		decided := sets[0] + sets[1]
+		if decided < minSets {
+			continue
+		}
+		// Compare sets[0]/decided without rounding
+		if found {
+			lhs, rhs := sets[0]*(bestSets[0]+bestSets[1]), bestSets[0]*decided
+			if lhs < rhs || (lhs == rhs && decided <= bestSets[0]+bestSets[1]) {
+				continue
+			}
+		}
+		bestKey, bestSets, found = key, sets, true
+	}
+	if !found {
+		return RecordRow{}, false
+	}
+	return RecordRow{
+		Name:    "Most lopsided rivalry",
+		Value:   fmt.Sprintf("%d-%d in sets", bestSets[0], bestSets[1]),
+		Players: []PlayerLink{g.playerLink(bestKey[0], "players/"), g.playerLink(bestKey[1], "players/")},
+		Links:   []PlayerLink{{Name: "Head-to-head", Href: g.h2hHref(bestKey[0], bestKey[1])}},
+	}, true
+}
+
+// tournamentLink links the page of a tournament.
+func (g *Generator) tournamentLink(id int, name string) PlayerLink {
+	return PlayerLink{
+		Name: tournamentTitle(id, name),
+		Href: fmt.Sprintf("tournaments/%d.html", id),
+	}
+}
+
+func (g *Generator) renderRecords(matches []storage.TournamentMatch) ([]byte, error) {
+	data := g.buildRecordsData(matches)
+	return executeTemplate("templates/records.tmpl", data)
+}
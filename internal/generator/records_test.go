@@ -0,0 +1,110 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+func TestRecords(t *testing.T) {
+	g := New("Rankings", "")
+	g.SetMatchupMinSets(2)
+	g.SetPlayerPages([]string{"Alice", "Bob"})
+
+	set := func(id, d int, p1, p2 string, w1, w2, b1, b2, a1, a2 int) storage.TournamentMatch {
+		return storage.TournamentMatch{
+			TournamentID: id, Date: day(d), Player1: p1, Player2: p2, Player1Wins: w1, Player2Wins: w2,
+			Player1ELOBefore: b1, Player2ELOBefore: b2, Player1ELOAfter: a1, Player2ELOAfter: a2,
+		}
+	}
+	matches := []storage.TournamentMatch{
+		set(1, 1, "Alice", "Bob", 2, 0, 1500, 1500, 1516, 1484),
+		set(1, 1, "Alice", "Carol", 2, 1, 1516, 1500, 1531, 1485),
+		set(2, 8, "Bob", "Alice", 0, 2, 1484, 1531, 1471, 1544),
+		set(2, 8, "Carol", "Alice", 2, 0, 1485, 1544, 1505, 1524),
+		// Unrated sets don't count
+		set(3, 15, "Carol", "Bob", 2, 0, 0, 0, 0, 0),
+		set(4, 22, "Bob", "Carol", 1, 1, 1471, 1505, 1473, 1503),
+	}
+	records := g.buildRecordsData(matches).Records
+
+	byName := make(map[string]RecordRow)
+	for _, r := range records {
+		byName[r.Name] = r
+	}
+	tests := []struct {
+		name, value, holder, date string
+	}{
+		{"Highest peak rating", "1544", "Alice", "Jan 8, 2024"},
+		{"Biggest tournament gain", "+31", "Alice", "Jan 1, 2024"},
+		{"Biggest upset", "59 points", "Carol", "Jan 8, 2024"},
+		{"Longest win streak", "3 sets", "Alice", "Jan 1, 2024 – Jan 8, 2024"},
+		{"Longest unbeaten streak", "3 sets", "Alice", "Jan 1, 2024 – Jan 8, 2024"},
+		{"Most sets played", "4 sets", "Alice", ""},
+		{"Most tournaments attended", "3 tournaments", "Bob", ""},
+		{"Most lopsided rivalry", "2-0 in sets", "Alice", ""},
+	}
+	if len(records) != len(tests) {
+		t.Errorf("expected %d records, got %d: %+v", len(tests), len(records), records)
+	}
+	for _, tt := range tests {
+		r, ok := byName[tt.name]
+		if !ok {
+			t.Errorf("missing record %q", tt.name)
+			continue
+		}
+		if r.Value != tt.value || r.Players[0].Name != tt.holder || r.Date != tt.date {
+			t.Errorf("%s: expected %s by %s on %q, got %+v", tt.name, tt.value, tt.holder, tt.date, r)
+		}
+	}
+
+	upset := byName["Biggest upset"]
+	if upset.Players[0].Href != "" || upset.Players[1].Href != "players/alice.html" {
+		t.Errorf("expected only ranked players to be linked, got %+v", upset.Players)
+	}
+	if len(upset.Links) != 2 || upset.Links[0].Href != "tournaments/2.html" || upset.Links[1].Href != "h2h/alice-vs-carol.html" {
+		t.Errorf("expected upset to link its tournament and head-to-head, got %+v", upset.Links)
+	}
+	if rivalry := byName["Most lopsided rivalry"]; rivalry.Players[1].Name != "Bob" || rivalry.Links[0].Href != "h2h/alice-vs-bob.html" {
+		t.Errorf("unexpected rivalry: %+v", rivalry)
+	}
+
+	page, err := g.RenderRecords(matches)
+	if err != nil {
+		t.Fatalf("RenderRecords failed: %v", err)
+	}
+	if !strings.Contains(string(page), `<span><a href="players/alice.html">Alice</a></span>`) {
+		t.Error("expected the records page to link record holders")
+	}
+
+	page, err = g.RenderRecords(nil)
+	if err != nil || !strings.Contains(string(page), "No rated sets yet") {
+		t.Errorf("expected an empty records page, got %v", err)
+	}
+}
+
+func TestRecordsTournamentGainIsNet(t *testing.T) {
+	g := New("Rankings", "")
+	matches := []storage.TournamentMatch{
+		// Dave climbs 80 points and gives 60 of them back at the same event
+		{TournamentID: 1, Date: day(1), Player1: "Dave", Player2: "Bob", Player1Wins: 2,
+			Player1ELOBefore: 1500, Player2ELOBefore: 1800, Player1ELOAfter: 1580, Player2ELOAfter: 1720},
+		{TournamentID: 1, Date: day(1), Player1: "Dave", Player2: "Carol", Player2Wins: 2,
+			Player1ELOBefore: 1580, Player2ELOBefore: 1300, Player1ELOAfter: 1520, Player2ELOAfter: 1360},
+		{TournamentID: 2, Date: day(8), Player1: "Erin", Player2: "Bob", Player1Wins: 2,
+			Player1ELOBefore: 1500, Player2ELOBefore: 1720, Player1ELOAfter: 1530, Player2ELOAfter: 1690},
+	}
+
+	for _, r := range g.buildRecordsData(matches).Records {
+		if r.Name != "Biggest tournament gain" {
+			continue
+		}
+		// Carol's +60 is the biggest net gain; Dave's is only +20
+		if r.Value != "+60" || r.Players[0].Name != "Carol" || r.Links[0].Href != "tournaments/1.html" {
+			t.Errorf("expected Carol's +60 at tournament 1, got %+v", r)
+		}
+		return
+	}
+	t.Error("missing tournament gain record")
+}
@@ -0,0 +1,114 @@
+package generator
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+// accentFolds maps common accented letters to their ASCII base letter.
+var accentFolds = func() map[rune]rune {
+	accented := []rune("àáâãäåçèéêëìíîïñòóôõöøùúûüýÿ")
+	plain := []rune("aaaaaaceeeeiiiinoooooouuuuyy")
+	folds := make(map[rune]rune, len(accented))
+	for i, r := range accented {
+		folds[r] = plain[i]
+	}
+	return folds
+}()
+
+// Slugify turns a player name into a lowercase ASCII file name: letters and
+// digits are kept, accents are dropped and everything else collapses into
+// single dashes. Names with nothing left get a stable hash-based slug.
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if folded, ok := accentFolds[r]; ok {
+			r = folded
+		}
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	if b.Len() == 0 {
+		sum := sha1.Sum([]byte(name))
+		return "player-" + hex.EncodeToString(sum[:4])
+	}
+	return b.String()
+}
+
+// Slugs assigns every player a unique slug. Names that slugify to the same
+// value get -2, -3, ... suffixes in the order they were added, so passing
+// players in creation order keeps existing slugs stable as players join.
+type Slugs struct {
+	byName map[string]string
+	bySlug map[string]string
+}
+
+// NewSlugs assigns slugs to names in order.
+func NewSlugs(names []string) *Slugs {
+	return newSlugs(names, nil)
+}
+
+// newSlugs is NewSlugs for pages that share a directory with other pages:
+// no name is assigned a slug in reserved.
+func newSlugs(names []string, reserved map[string]bool) *Slugs {
+	s := &Slugs{
+		byName: make(map[string]string, len(names)),
+		bySlug: make(map[string]string, len(names)),
+	}
+	for _, name := range names {
+		if _, ok := s.byName[name]; ok {
+			continue
+		}
+		base := Slugify(name)
+		slug := base
+		for n := 2; s.bySlug[slug] != "" || reserved[slug]; n++ {
+			slug = fmt.Sprintf("%s-%d", base, n)
+		}
+		s.byName[name] = slug
+		s.bySlug[slug] = name
+	}
+	return s
+}
+
+// PlayerSlugs assigns slugs to players in the order they are given, which
+// for GetAllPlayers is creation order, so a player's slug does not change
+// when someone with a clashing name joins later.
+func PlayerSlugs(players []storage.Player) *Slugs {
+	names := make([]string, len(players))
+	for i, p := range players {
+		names[i] = p.DisplayName
+	}
+	return NewSlugs(names)
+}
+
+// Slug returns the slug of name. Names NewSlugs was not given fall back to
+// Slugify, so the result may collide.
+func (s *Slugs) Slug(name string) string {
+	if s != nil {
+		if slug, ok := s.byName[name]; ok {
+			return slug
+		}
+	}
+	return Slugify(name)
+}
+
+// Name returns the player a slug was assigned to.
+func (s *Slugs) Name(slug string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	name, ok := s.bySlug[slug]
+	return name, ok
+}
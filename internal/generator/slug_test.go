@@ -0,0 +1,72 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Alice":            "alice",
+		"Szitsu Szitsu":    "szitsu-szitsu",
+		"AC/DC":            "ac-dc",
+		"  _Boreas_  ":     "boreas",
+		"Mr. Game & Watch": "mr-game-watch",
+		"Émile":            "emile",
+	}
+	for name, want := range tests {
+		if got := Slugify(name); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", name, got, want)
+		}
+	}
+
+	if got := Slugify("ヨシ"); !strings.HasPrefix(got, "player-") || got != Slugify("ヨシ") {
+		t.Errorf("Slugify of a name without ASCII = %q, want a stable player- slug", got)
+	}
+	if Slugify("ヨシ") == Slugify("ピカ") {
+		t.Error("different names without ASCII should get different slugs")
+	}
+}
+
+func TestSlugsCollisions(t *testing.T) {
+	slugs := NewSlugs([]string{"Alice", "alice", "ALICE!", "alice-2", "Bob"})
+
+	want := map[string]string{
+		"Alice":   "alice",
+		"alice":   "alice-2",
+		"ALICE!":  "alice-3",
+		"alice-2": "alice-2-2",
+		"Bob":     "bob",
+	}
+	for name, slug := range want {
+		if got := slugs.Slug(name); got != slug {
+			t.Errorf("Slug(%q) = %q, want %q", name, got, slug)
+		}
+		if got, ok := slugs.Name(slug); !ok || got != name {
+			t.Errorf("Name(%q) = %q, %v, want %q", slug, got, ok, name)
+		}
+	}
+
+	if got := slugs.Slug("Carol"); got != "carol" {
+		t.Errorf("unknown names should be slugified, got %q", got)
+	}
+	if _, ok := slugs.Name("carol"); ok {
+		t.Error("Name should only know assigned slugs")
+	}
+}
+
+func TestGenerateRedirect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Szitsu Szitsu.html")
+	if err := New("", "").GenerateRedirect("szitsu-szitsu.html", path); err != nil {
+		t.Fatal(err)
+	}
+	page, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), `content="0; url=szitsu-szitsu.html"`) {
+		t.Errorf("redirect page does not refresh to the slug:\n%s", page)
+	}
+}
@@ -0,0 +1,247 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+// TournamentsData is the data passed to the tournament list template.
+// It is also the data of api/tournaments.json.
+type TournamentsData struct {
+	Title       string          `json:"-"`
+	Timestamp   string          `json:"-"`
+	Tournaments []TournamentRow `json:"tournaments"`
+}
+
+// TournamentRow describes one stored tournament. Date is empty while the
+// tournament is waiting for one.
+type TournamentRow struct {
+	MeleeID     int    `json:"melee_id"`
+	Title       string `json:"-"`
+	Date        string `json:"date"`
+	DisplayDate string `json:"-"`
+	Name        string `json:"name"`
+	Tier        string `json:"tier"`
+	Location    string `json:"location"`
+	Organizer   string `json:"organizer"`
+	Rated       bool   `json:"rated"`
+}
+
+// TournamentData is the data passed to the tournament template.
+type TournamentData struct {
+	Title     string
+	MeleeID   int
+	Date      string
+	Tier      string
+	Location  string
+	Organizer string
+	Rated     bool
+	Timestamp string
+	Entrants  int
+	Standings []TournamentStandingRow
+	Rounds    []TournamentRound
+	// Upset is the win over the highest rated opponent relative to the
+	// winner, or nil if every match went to the favourite.
+	Upset *TournamentMatchRow
+}
+
+// TournamentStandingRow is one player's record and rating change over a
+// tournament.
+type TournamentStandingRow struct {
+	Player           PlayerLink
+	Wins             int
+	Losses           int
+	Draws            int
+	ELOBefore        int
+	ELOAfter         int
+	Change           int
+	ChangeClass      string
+	Performance      int
+	PerformanceClass string
+}
+
+// TournamentRound holds the matches of one round.
+type TournamentRound struct {
+	Round   int
+	Matches []TournamentMatchRow
+}
+
+// TournamentMatchRow is one match of a tournament. Winner is 1 or 2, or 0
+// for a draw.
+type TournamentMatchRow struct {
+	Round            int
+	Player1          PlayerLink
+	Player2          PlayerLink
+	Player1Wins      int
+	Player2Wins      int
+	Player1ELOBefore int
+	Player2ELOBefore int
+	Player1ELOAfter  int
+	Player2ELOAfter  int
+	Winner           int
+	// Gap is how many points the winner was rated below the loser.
+	Gap int
+}
+
+// PlayerLink is a player name with the relative URL of their page, which is
+// empty if they have none.
+type PlayerLink struct {
+	Name string
+	Href string
+}
+
+// tournamentTitle names a tournament, falling back to its melee.gg ID.
+func tournamentTitle(meleeID int, name string) string {
+	if name != "" {
+		return name
+	}
+	return fmt.Sprintf("Tournament #%d", meleeID)
+}
+
+// buildTournamentsData lists tournaments newest first.
+func (g *Generator) buildTournamentsData(tournaments []storage.Tournament) TournamentsData {
+	rows := make([]TournamentRow, 0, len(tournaments))
+	for i := len(tournaments) - 1; i >= 0; i-- {
+		t := tournaments[i]
+		row := TournamentRow{
+			MeleeID:   t.MeleeID,
+			Title:     tournamentTitle(t.MeleeID, t.Name),
+			Name:      t.Name,
+			Tier:      t.Tier,
+			Location:  t.Location,
+			Organizer: t.Organizer,
+			Rated:     t.Rated,
+		}
+		if !t.Date.IsZero() {
+			row.Date = t.Date.Format("2006-01-02")
+			row.DisplayDate = t.Date.Format("Jan 2, 2006")
+		}
+		rows = append(rows, row)
+	}
+	return TournamentsData{
+		Title:       g.title,
+		Timestamp:   time.Now().Format("January 2, 2006 15:04"),
+		Tournaments: rows,
+	}
+}
+
+func (g *Generator) buildTournamentData(t storage.Tournament, matches []storage.TournamentMatch) TournamentData {
+	data := TournamentData{
+		Title:     tournamentTitle(t.MeleeID, t.Name),
+		MeleeID:   t.MeleeID,
+		Tier:      t.Tier,
+		Location:  t.Location,
+		Organizer: t.Organizer,
+		Rated:     t.Rated,
+		Timestamp: time.Now().Format("January 2, 2006 15:04"),
+	}
+	if !t.Date.IsZero() {
+		data.Date = t.Date.Format("January 2, 2006")
+	}
+
+	standings := make(map[string]*TournamentStandingRow)
+	standing := func(name string, eloBefore int) *TournamentStandingRow {
+		row, ok := standings[name]
+		if !ok {
+			// Matches come in round order, so the first one holds the
+			// rating the player entered with
+			row = &TournamentStandingRow{Player: g.playerLink(name, "../players/"), ELOBefore: eloBefore}
+			standings[name] = row
+		}
+		return row
+	}
+
+	for _, m := range matches {
+		row := TournamentMatchRow{
+			Round:            m.Round,
+			Player1:          g.playerLink(m.Player1, "../players/"),
+			Player2:          g.playerLink(m.Player2, "../players/"),
+			Player1Wins:      m.Player1Wins,
+			Player2Wins:      m.Player2Wins,
+			Player1ELOBefore: m.Player1ELOBefore,
+			Player2ELOBefore: m.Player2ELOBefore,
+			Player1ELOAfter:  m.Player1ELOAfter,
+			Player2ELOAfter:  m.Player2ELOAfter,
+		}
+		p1 := standing(m.Player1, m.Player1ELOBefore)
+		p2 := standing(m.Player2, m.Player2ELOBefore)
+		p1.ELOAfter = m.Player1ELOAfter
+		p2.ELOAfter = m.Player2ELOAfter
+
+		switch {
+		case m.Player1Wins > m.Player2Wins:
+			row.Winner = 1
+			row.Gap = m.Player2ELOBefore - m.Player1ELOBefore
+			p1.Wins++
+			p2.Losses++
+		case m.Player2Wins > m.Player1Wins:
+			row.Winner = 2
+			row.Gap = m.Player1ELOBefore - m.Player2ELOBefore
+			p2.Wins++
+			p1.Losses++
+		default:
+			p1.Draws++
+			p2.Draws++
+		}
+
+		if n := len(data.Rounds); n == 0 || data.Rounds[n-1].Round != m.Round {
+			data.Rounds = append(data.Rounds, TournamentRound{Round: m.Round})
+		}
+		round := &data.Rounds[len(data.Rounds)-1]
+		round.Matches = append(round.Matches, row)
+
+		if t.Rated && row.Winner != 0 && row.Gap > 0 && (data.Upset == nil || row.Gap > data.Upset.Gap) {
+			upset := row
+			data.Upset = &upset
+		}
+	}
+
+	if t.Rated {
+		for _, p := range storage.Performances(matches) {
+			standings[p.Player].Performance = p.Performance
+		}
+	}
+	for _, row := range standings {
+		if t.Rated {
+			row.Change = row.ELOAfter - row.ELOBefore
+		}
+		row.ChangeClass = "neutral"
+		if row.Change > 0 {
+			row.ChangeClass = "positive"
+		} else if row.Change < 0 {
+			row.ChangeClass = "negative"
+		}
+		if t.Rated {
+			row.PerformanceClass = performanceClass(row.Performance, row.ELOBefore)
+		}
+		data.Standings = append(data.Standings, *row)
+	}
+	sort.Slice(data.Standings, func(i, j int) bool {
+		a, b := data.Standings[i], data.Standings[j]
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		if a.Losses != b.Losses {
+			return a.Losses < b.Losses
+		}
+		if a.Performance != b.Performance {
+			return a.Performance > b.Performance
+		}
+		return a.Player.Name < b.Player.Name
+	})
+	data.Entrants = len(data.Standings)
+	return data
+}
+
+func (g *Generator) renderTournament(t storage.Tournament, matches []storage.TournamentMatch) ([]byte, error) {
+	data := g.buildTournamentData(t, matches)
+	return executeTemplate("templates/tournament.tmpl", data)
+}
+
+func (g *Generator) renderTournaments(tournaments []storage.Tournament) ([]byte, error) {
+	data := g.buildTournamentsData(tournaments)
+	return executeTemplate("templates/tournaments.tmpl", data)
+}
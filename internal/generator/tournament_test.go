@@ -0,0 +1,74 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+func TestBuildTournamentData(t *testing.T) {
+	g := New("Rankings", "")
+	g.SetPlayerPages([]string{"Alice", "Bob"})
+	tournament := storage.Tournament{MeleeID: 42, Date: time.Date(2024, 5, 4, 0, 0, 0, 0, time.UTC), Rated: true}
+	matches := []storage.TournamentMatch{
+		{Round: 1, Player1: "Alice", Player2: "Carol", Player1Wins: 2, Player2Wins: 0,
+			Player1ELOBefore: 1600, Player2ELOBefore: 1400, Player1ELOAfter: 1605, Player2ELOAfter: 1395},
+		{Round: 1, Player1: "Bob", Player2: "Dave", Player1Wins: 1, Player2Wins: 2,
+			Player1ELOBefore: 1550, Player2ELOBefore: 1450, Player1ELOAfter: 1530, Player2ELOAfter: 1470},
+		{Round: 2, Player1: "Dave", Player2: "Alice", Player1Wins: 2, Player2Wins: 1,
+			Player1ELOBefore: 1470, Player2ELOBefore: 1605, Player1ELOAfter: 1497, Player2ELOAfter: 1578},
+	}
+
+	data := g.buildTournamentData(tournament, matches)
+
+	if data.Title != "Tournament #42" || data.Date != "May 4, 2024" || data.Entrants != 4 {
+		t.Errorf("unexpected header: %q %q %d", data.Title, data.Date, data.Entrants)
+	}
+	if len(data.Rounds) != 2 || len(data.Rounds[0].Matches) != 2 || data.Rounds[1].Round != 2 {
+		t.Fatalf("expected matches grouped by round, got %+v", data.Rounds)
+	}
+
+	dave := data.Standings[0]
+	if dave.Player.Name != "Dave" || dave.Wins != 2 || dave.ELOBefore != 1450 || dave.ELOAfter != 1497 || dave.Change != 47 {
+		t.Errorf("expected Dave to lead the standings, got %+v", dave)
+	}
+	// Dave won both, which is capped at 800 above his strongest opponent
+	if dave.Performance != 2405 || dave.PerformanceClass != "positive" {
+		t.Errorf("expected performance 2405 above his rating, got %d (%s)", dave.Performance, dave.PerformanceClass)
+	}
+	if dave.Player.Href != "" {
+		t.Errorf("unranked players should not be linked, got %q", dave.Player.Href)
+	}
+	if alice := data.Rounds[0].Matches[0].Player1; alice.Href != "../players/alice.html" {
+		t.Errorf("expected a link to Alice's page, got %q", alice.Href)
+	}
+
+	if data.Upset == nil || data.Upset.Round != 2 || data.Upset.Gap != 135 {
+		t.Errorf("expected Dave over Alice as the biggest upset, got %+v", data.Upset)
+	}
+
+	page, err := g.RenderTournamentPage(tournament, matches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), "Biggest Upset") {
+		t.Error("expected the upset on the page")
+	}
+}
+
+func TestBuildTournamentDataUnrated(t *testing.T) {
+	g := New("Rankings", "")
+	data := g.buildTournamentData(storage.Tournament{MeleeID: 7, Name: "Weekly #7"}, []storage.TournamentMatch{
+		{Round: 1, Player1: "Alice", Player2: "Bob", Player1Wins: 0, Player2Wins: 2},
+	})
+	if data.Upset != nil {
+		t.Errorf("unrated tournaments have no upsets, got %+v", data.Upset)
+	}
+	for _, row := range data.Standings {
+		if row.Change != 0 || row.Performance != 0 {
+			t.Errorf("unrated tournaments have no rating changes, got %+v", row)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package impact
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+// Standing is a player's rating and rank at one point in time.
+// Rank is 0 when the player is not eligible for the rankings.
+type Standing struct {
+	DisplayName string
+	ELO         int
+	Rank        int
+}
+
+// Snapshot holds the standing of every player, keyed by player ID.
+type Snapshot map[int64]Standing
+
+// Take records the current standing of every player in the store. Players
+// are ranked the way the site ranks them, among those with at least
+// minMatches matches.
+func Take(store *storage.Storage, minMatches int) (Snapshot, error) {
+	players, err := store.GetAllPlayers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get players: %w", err)
+	}
+
+	ranks := make(map[int64]int, len(players))
+	for _, r := range storage.RankPlayers(players, minMatches) {
+		ranks[r.PlayerID] = r.Rank
+	}
+
+	snapshot := make(Snapshot, len(players))
+	for _, p := range players {
+		snapshot[p.ID] = Standing{
+			DisplayName: p.DisplayName,
+			ELO:         p.CurrentELO,
+			Rank:        ranks[p.ID],
+		}
+	}
+	return snapshot, nil
+}
+
+// Change describes how one player's standing moved between two snapshots.
+type Change struct {
+	DisplayName string `json:"display_name"`
+	ELOBefore   int    `json:"elo_before"`
+	ELOAfter    int    `json:"elo_after"`
+	RankBefore  int    `json:"rank_before"`
+	RankAfter   int    `json:"rank_after"`
+	New         bool   `json:"new"`
+}
+
+// Delta is the rating change, or 0 for new players.
+func (c Change) Delta() int {
+	if c.New {
+		return 0
+	}
+	return c.ELOAfter - c.ELOBefore
+}
+
+// Report lists the players whose standing changed.
+type Report struct {
+	Changes []Change `json:"changes"`
+}
+
+// Compare returns the players that are new in after, or whose rating or rank
+// differs from before. Changes are ordered by rank after, then by rating.
+func Compare(before, after Snapshot) Report {
+	var changes []Change
+	for id, a := range after {
+		b, existed := before[id]
+		if existed && b.ELO == a.ELO && b.Rank == a.Rank {
+			continue
+		}
+		c := Change{
+			DisplayName: a.DisplayName,
+			ELOAfter:    a.ELO,
+			RankAfter:   a.Rank,
+			New:         !existed,
+		}
+		if existed {
+			c.ELOBefore = b.ELO
+			c.RankBefore = b.Rank
+		}
+		changes = append(changes, c)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		ri, rj := changes[i].RankAfter, changes[j].RankAfter
+		if (ri == 0) != (rj == 0) {
+			return ri != 0
+		}
+		if ri != rj {
+			return ri < rj
+		}
+		if changes[i].ELOAfter != changes[j].ELOAfter {
+			return changes[i].ELOAfter > changes[j].ELOAfter
+		}
+		return changes[i].DisplayName < changes[j].DisplayName
+	})
+
+	return Report{Changes: changes}
+}
+
+// Print writes the report as a plain-text table.
+func (r Report) Print(w io.Writer) {
+	if len(r.Changes) == 0 {
+		fmt.Fprintln(w, "No rating changes")
+		return
+	}
+
+	fmt.Fprintf(w, "%-24s %6s %6s %6s  %s\n", "Player", "Before", "After", "Delta", "Rank")
+	for _, c := range r.Changes {
+		before, delta := "-", "-"
+		if !c.New {
+			before = fmt.Sprintf("%d", c.ELOBefore)
+			delta = fmt.Sprintf("%+d", c.Delta())
+		}
+		fmt.Fprintf(w, "%-24s %6s %6d %6s  %s\n", c.DisplayName, before, c.ELOAfter, delta, rankChange(c))
+	}
+}
+
+func rankChange(c Change) string {
+	switch {
+	case c.New && c.RankAfter == 0:
+		return "NEW (unranked)"
+	case c.New:
+		return fmt.Sprintf("NEW #%d", c.RankAfter)
+	case c.RankBefore == c.RankAfter && c.RankAfter == 0:
+		return "unranked"
+	case c.RankBefore == c.RankAfter:
+		return fmt.Sprintf("#%d", c.RankAfter)
+	case c.RankBefore == 0:
+		return fmt.Sprintf("unranked -> #%d", c.RankAfter)
+	case c.RankAfter == 0:
+		return fmt.Sprintf("#%d -> unranked", c.RankBefore)
+	default:
+		return fmt.Sprintf("#%d -> #%d", c.RankBefore, c.RankAfter)
+	}
+}
@@ -0,0 +1,111 @@
+package impact
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+func TestCompare(t *testing.T) {
+	before := Snapshot{
+		1: {DisplayName: "Alice", ELO: 1600, Rank: 1},
+		2: {DisplayName: "Bob", ELO: 1550, Rank: 2},
+		3: {DisplayName: "Charlie", ELO: 1500, Rank: 3},
+	}
+	after := Snapshot{
+		1: {DisplayName: "Alice", ELO: 1580, Rank: 2},
+		2: {DisplayName: "Bob", ELO: 1590, Rank: 1},
+		3: {DisplayName: "Charlie", ELO: 1500, Rank: 3},
+		4: {DisplayName: "Dave", ELO: 1520, Rank: 0},
+	}
+
+	report := Compare(before, after)
+
+	if len(report.Changes) != 3 {
+		t.Fatalf("expected 3 changes, got %d: %+v", len(report.Changes), report.Changes)
+	}
+
+	bob := report.Changes[0]
+	if bob.DisplayName != "Bob" || bob.RankBefore != 2 || bob.RankAfter != 1 || bob.Delta() != 40 {
+		t.Errorf("unexpected change for Bob: %+v", bob)
+	}
+
+	alice := report.Changes[1]
+	if alice.DisplayName != "Alice" || alice.Delta() != -20 {
+		t.Errorf("unexpected change for Alice: %+v", alice)
+	}
+
+	dave := report.Changes[2]
+	if dave.DisplayName != "Dave" || !dave.New {
+		t.Errorf("expected Dave to be new, got %+v", dave)
+	}
+	if dave.Delta() != 0 {
+		t.Errorf("expected zero delta for new player, got %d", dave.Delta())
+	}
+}
+
+func TestReportPrint(t *testing.T) {
+	report := Report{Changes: []Change{
+		{DisplayName: "Bob", ELOBefore: 1550, ELOAfter: 1590, RankBefore: 2, RankAfter: 1},
+		{DisplayName: "Dave", ELOAfter: 1520, New: true},
+	}}
+
+	var buf bytes.Buffer
+	report.Print(&buf)
+	out := buf.String()
+
+	if !strings.Contains(out, "#2 -> #1") {
+		t.Errorf("expected rank change in output, got:\n%s", out)
+	}
+	if !strings.Contains(out, "+40") {
+		t.Errorf("expected rating delta in output, got:\n%s", out)
+	}
+	if !strings.Contains(out, "NEW (unranked)") {
+		t.Errorf("expected new player marker in output, got:\n%s", out)
+	}
+}
+
+func TestReportPrintEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	Report{}.Print(&buf)
+	if !strings.Contains(buf.String(), "No rating changes") {
+		t.Errorf("expected empty report message, got %q", buf.String())
+	}
+}
+
+func TestTake(t *testing.T) {
+	store, err := storage.New(filepath.Join(t.TempDir(), "rankings.db"))
+	if err != nil {
+		t.Fatalf("failed to open storage: %v", err)
+	}
+	defer store.Close()
+
+	// Two players share a name; ties on rating go by name, then ID
+	var players []storage.Player
+	for i, p := range []struct {
+		name    string
+		elo     int
+		matches int
+	}{{"Alice", 1600, 3}, {"Alice", 1700, 3}, {"Bob", 1600, 3}, {"Carol", 1800, 1}} {
+		player, _ := store.GetOrCreatePlayer(int64(i+1), p.name, "")
+		player.CurrentELO, player.MatchesPlayed = p.elo, p.matches
+		players = append(players, *player)
+	}
+	if err := store.ApplyRatingUpdate(storage.RatingUpdate{Players: players}); err != nil {
+		t.Fatalf("failed to store ratings: %v", err)
+	}
+
+	snapshot, err := Take(store, 2)
+	if err != nil {
+		t.Fatalf("Take failed: %v", err)
+	}
+	want := map[int64]int{players[1].ID: 1, players[0].ID: 2, players[2].ID: 3, players[3].ID: 0}
+	for id, rank := range want {
+		if got := snapshot[id].Rank; got != rank {
+			t.Errorf("player %d (%s): expected rank %d, got %d", id, snapshot[id].DisplayName, rank, got)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Metadata describes a tournament beyond what the melee export contains.
+// Empty fields are unknown.
+type Metadata struct {
+	Date      string `json:"date,omitempty" yaml:"date,omitempty"`
+	Name      string `json:"name,omitempty" yaml:"name,omitempty"`
+	Tier      string `json:"tier,omitempty" yaml:"tier,omitempty"`
+	Location  string `json:"location,omitempty" yaml:"location,omitempty"`
+	Organizer string `json:"organizer,omitempty" yaml:"organizer,omitempty"`
+}
+
+// ParsedDate returns the date as a time, or the zero time if it is unset.
+func (m Metadata) ParsedDate() (time.Time, error) {
+	if m.Date == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse("2006-01-02", m.Date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", m.Date)
+	}
+	return t, nil
+}
+
+// Manifest maps melee tournament IDs to their metadata.
+type Manifest map[int]Metadata
+
+type manifestFile struct {
+	Tournaments Manifest `json:"tournaments" yaml:"tournaments"`
+}
+
+// Load reads a tournaments manifest. YAML is used for .yaml and .yml files,
+// JSON otherwise. If path does not exist, the same name with the other
+// extensions is tried; an empty manifest is returned if none exists.
+func Load(path string) (Manifest, error) {
+	if path == "" {
+		return Manifest{}, nil
+	}
+
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	candidates := []string{path, base + ".yaml", base + ".yml", base + ".json"}
+	for _, candidate := range candidates {
+		data, err := os.ReadFile(candidate)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var file manifestFile
+		if err := unmarshal(candidate, data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", candidate, err)
+		}
+		if file.Tournaments == nil {
+			file.Tournaments = Manifest{}
+		}
+		return file.Tournaments, nil
+	}
+
+	return Manifest{}, nil
+}
+
+// sidecarExtensions are tried in order when looking for a sidecar file.
+var sidecarExtensions = []string{".meta.yaml", ".meta.yml", ".meta.json"}
+
+// IsSidecar reports whether filename is a metadata sidecar rather than a
+// match export.
+func IsSidecar(filename string) bool {
+	for _, ext := range sidecarExtensions {
+		if strings.HasSuffix(filename, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// SidecarPaths returns the sidecar files that may accompany an export, e.g.
+// Matches-tournament-1.meta.yaml for Matches-tournament-1.json.
+func SidecarPaths(exportPath string) []string {
+	base := strings.TrimSuffix(exportPath, filepath.Ext(exportPath))
+	paths := make([]string, len(sidecarExtensions))
+	for i, ext := range sidecarExtensions {
+		paths[i] = base + ext
+	}
+	return paths
+}
+
+// LoadSidecar reads the sidecar of an export file. It returns false if the
+// export has no sidecar.
+func LoadSidecar(exportPath string) (Metadata, bool, error) {
+	for _, path := range SidecarPaths(exportPath) {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return Metadata{}, false, err
+		}
+
+		var meta Metadata
+		if err := unmarshal(path, data, &meta); err != nil {
+			return Metadata{}, false, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		return meta, true, nil
+	}
+	return Metadata{}, false, nil
+}
+
+func unmarshal(path string, data []byte, v interface{}) error {
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		return yaml.Unmarshal(data, v)
+	default:
+		return json.Unmarshal(data, v)
+	}
+}
+
+// Conflict is a field that two metadata sources disagree on.
+type Conflict struct {
+	Field    string
+	Base     string
+	Override string
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s: %q vs %q", c.Field, c.Base, c.Override)
+}
+
+// Merge returns base with every non-empty field of override applied, along
+// with the fields where both were set to different values.
+func Merge(base, override Metadata) (Metadata, []Conflict) {
+	merged := base
+	var conflicts []Conflict
+
+	fields := []struct {
+		name     string
+		dst      *string
+		override string
+	}{
+		{"date", &merged.Date, override.Date},
+		{"name", &merged.Name, override.Name},
+		{"tier", &merged.Tier, override.Tier},
+		{"location", &merged.Location, override.Location},
+		{"organizer", &merged.Organizer, override.Organizer},
+	}
+
+	for _, f := range fields {
+		if f.override == "" {
+			continue
+		}
+		if *f.dst != "" && *f.dst != f.override {
+			conflicts = append(conflicts, Conflict{Field: f.name, Base: *f.dst, Override: f.override})
+		}
+		*f.dst = f.override
+	}
+
+	return merged, conflicts
+}
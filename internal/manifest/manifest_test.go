@@ -0,0 +1,125 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestLoadYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tournaments.yaml")
+	writeFile(t, path, `tournaments:
+  170676:
+    date: 2024-08-31
+    name: "Weekly #12"
+    tier: local
+  172453:
+    location: Berlin
+`)
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	if len(m) != 2 {
+		t.Fatalf("expected 2 tournaments, got %d", len(m))
+	}
+	if m[170676].Date != "2024-08-31" {
+		t.Errorf("expected unquoted date to decode as 2024-08-31, got %q", m[170676].Date)
+	}
+	if m[170676].Name != "Weekly #12" || m[170676].Tier != "local" {
+		t.Errorf("unexpected metadata: %+v", m[170676])
+	}
+	if m[172453].Location != "Berlin" {
+		t.Errorf("expected location Berlin, got %q", m[172453].Location)
+	}
+}
+
+func TestLoadFallsBackToJSON(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tournaments.json"), `{"tournaments": {"1": {"date": "2024-01-02", "organizer": "Club"}}}`)
+
+	m, err := Load(filepath.Join(dir, "tournaments.yaml"))
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	if m[1].Date != "2024-01-02" || m[1].Organizer != "Club" {
+		t.Errorf("unexpected metadata: %+v", m[1])
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	m, err := Load(filepath.Join(t.TempDir(), "tournaments.yaml"))
+	if err != nil {
+		t.Fatalf("expected missing manifest to be empty, got %v", err)
+	}
+	if len(m) != 0 {
+		t.Errorf("expected empty manifest, got %+v", m)
+	}
+}
+
+func TestLoadSidecar(t *testing.T) {
+	dir := t.TempDir()
+	export := filepath.Join(dir, "Matches-tournament-1.json")
+	writeFile(t, filepath.Join(dir, "Matches-tournament-1.meta.yml"), "date: 2024-03-04\nname: Regional\n")
+
+	meta, found, err := LoadSidecar(export)
+	if err != nil {
+		t.Fatalf("failed to load sidecar: %v", err)
+	}
+	if !found {
+		t.Fatal("expected sidecar to be found")
+	}
+	if meta.Date != "2024-03-04" || meta.Name != "Regional" {
+		t.Errorf("unexpected metadata: %+v", meta)
+	}
+
+	_, found, err = LoadSidecar(filepath.Join(dir, "Matches-tournament-2.json"))
+	if err != nil || found {
+		t.Errorf("expected no sidecar, got found=%v err=%v", found, err)
+	}
+}
+
+func TestIsSidecar(t *testing.T) {
+	tests := map[string]bool{
+		"Matches-tournament-1.meta.yaml": true,
+		"Matches-tournament-1.meta.json": true,
+		"Matches-tournament-1.json":      false,
+		"tournaments.yaml":               false,
+	}
+	for name, want := range tests {
+		if got := IsSidecar(name); got != want {
+			t.Errorf("IsSidecar(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := Metadata{Date: "2024-01-01", Name: "Weekly", Tier: "local"}
+	override := Metadata{Date: "2024-01-02", Tier: "local", Location: "Berlin"}
+
+	merged, conflicts := Merge(base, override)
+	want := Metadata{Date: "2024-01-02", Name: "Weekly", Tier: "local", Location: "Berlin"}
+	if merged != want {
+		t.Errorf("expected %+v, got %+v", want, merged)
+	}
+	if len(conflicts) != 1 || conflicts[0].Field != "date" || conflicts[0].Base != "2024-01-01" {
+		t.Errorf("expected one date conflict, got %+v", conflicts)
+	}
+}
+
+func TestParsedDate(t *testing.T) {
+	if d, err := (Metadata{}).ParsedDate(); err != nil || !d.IsZero() {
+		t.Errorf("expected zero date for unset date, got %v, %v", d, err)
+	}
+	if _, err := (Metadata{Date: "31/08/2024"}).ParsedDate(); err == nil {
+		t.Error("expected error for invalid date")
+	}
+}
@@ -37,7 +37,11 @@ func (p *Parser) ParseFile(filepath string, tournamentID int) ([]Match, error) {
 	if err != nil {
 		return nil, err
 	}
+	return p.ParseBytes(data, tournamentID)
+}
 
+// ParseBytes parses the contents of a melee match export.
+func (p *Parser) ParseBytes(data []byte, tournamentID int) ([]Match, error) {
 	// Try new format first (V2)
 	var rawMatchesV2 []RawMatchV2
 	if err := json.Unmarshal(data, &rawMatchesV2); err == nil && len(rawMatchesV2) > 0 {
@@ -64,3 +64,21 @@ func TestParseNonExistentFile(t *testing.T) {
 		t.Error("expected error for nonexistent file")
 	}
 }
+
+func TestParseBytes(t *testing.T) {
+	parser := New()
+
+	data := []byte(`[{"RoundNumber": 1, "Team1Id": 10, "Team1": "Alice", "Team1WinsAndByes": 2,
+		"Team2Id": 20, "Team2": "Bob", "Team2WinsAndByes": 1, "HasResult": true}]`)
+	matches, err := parser.ParseBytes(data, 42)
+	if err != nil {
+		t.Fatalf("failed to parse bytes: %v", err)
+	}
+	if len(matches) != 1 || matches[0].TournamentID != 42 {
+		t.Fatalf("expected 1 match for tournament 42, got %+v", matches)
+	}
+
+	if _, err := parser.ParseBytes([]byte("not valid json"), 1); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
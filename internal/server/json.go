@@ -0,0 +1,138 @@
+package server
+
+import (
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+type errorJSON struct {
+	Error string `json:"error"`
+}
+
+// pageJSON wraps one page of a list.
+type pageJSON struct {
+	Page    int         `json:"page"`
+	PerPage int         `json:"per_page"`
+	Total   int         `json:"total"`
+	Items   interface{} `json:"items"`
+}
+
+type rankingJSON struct {
+	Rank          int     `json:"rank"`
+	DisplayName   string  `json:"display_name"`
+	Slug          string  `json:"slug"`
+	ELO           int     `json:"elo"`
+	MatchesPlayed int     `json:"matches_played"`
+	Wins          int     `json:"wins"`
+	Losses        int     `json:"losses"`
+	WinRate       float64 `json:"win_rate"`
+}
+
+func newRankingJSON(r storage.Ranking, slug string) rankingJSON {
+	return rankingJSON{
+		Rank:          r.Rank,
+		DisplayName:   r.DisplayName,
+		Slug:          slug,
+		ELO:           r.CurrentELO,
+		MatchesPlayed: r.MatchesPlayed,
+		Wins:          r.Wins,
+		Losses:        r.Losses,
+		WinRate:       r.WinRate,
+	}
+}
+
+// playerJSON is a player's standing with a page of their match history.
+// Rank is 0 for unranked players.
+type playerJSON struct {
+	rankingJSON
+	History pageJSON `json:"history"`
+}
+
+type playerMatchJSON struct {
+	Date         string `json:"date"`
+	Round        int    `json:"round"`
+	Opponent     string `json:"opponent"`
+	PlayerWins   int    `json:"player_wins"`
+	OpponentWins int    `json:"opponent_wins"`
+	Result       string `json:"result"`
+	ELOBefore    int    `json:"elo_before"`
+	ELOAfter     int    `json:"elo_after"`
+}
+
+func newPlayerMatchJSON(m storage.PlayerMatch) playerMatchJSON {
+	return playerMatchJSON{
+		Date:         m.DatePlayed.Format("2006-01-02"),
+		Round:        m.Round,
+		Opponent:     m.OpponentName,
+		PlayerWins:   m.PlayerWins,
+		OpponentWins: m.OpponentWins,
+		Result:       m.Result,
+		ELOBefore:    m.PlayerELOBefore,
+		ELOAfter:     m.PlayerELOAfter,
+	}
+}
+
+type matchupJSON struct {
+	Player1           string  `json:"player1"`
+	Player2           string  `json:"player2"`
+	Player1Sets       int     `json:"player1_sets"`
+	Player2Sets       int     `json:"player2_sets"`
+	SetsPlayed        int     `json:"sets_played"`
+	Player1SetWinRate float64 `json:"player1_set_win_rate"`
+	Player1Wins       int     `json:"player1_wins"`
+	Player2Wins       int     `json:"player2_wins"`
+	GamesPlayed       int     `json:"games_played"`
+	Player1WinRate    float64 `json:"player1_win_rate"`
+}
+
+func newMatchupJSON(m storage.Matchup) matchupJSON {
+	return matchupJSON{
+		Player1:           m.Player1,
+		Player2:           m.Player2,
+		Player1Sets:       m.Player1Sets,
+		Player2Sets:       m.Player2Sets,
+		SetsPlayed:        m.SetsPlayed,
+		Player1SetWinRate: m.Player1SetWinRate,
+		Player1Wins:       m.Player1Wins,
+		Player2Wins:       m.Player2Wins,
+		GamesPlayed:       m.GamesPlayed,
+		Player1WinRate:    m.Player1WinRate,
+	}
+}
+
+type tournamentJSON struct {
+	MeleeID   int    `json:"melee_id"`
+	Date      string `json:"date,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Tier      string `json:"tier,omitempty"`
+	Location  string `json:"location,omitempty"`
+	Organizer string `json:"organizer,omitempty"`
+	Rated     bool   `json:"rated"`
+}
+
+func newTournamentJSON(t storage.Tournament) tournamentJSON {
+	tj := tournamentJSON{
+		MeleeID:   t.MeleeID,
+		Name:      t.Name,
+		Tier:      t.Tier,
+		Location:  t.Location,
+		Organizer: t.Organizer,
+		Rated:     t.Rated,
+	}
+	if !t.Date.IsZero() {
+		tj.Date = t.Date.Format("2006-01-02")
+	}
+	return tj
+}
+
+type tournamentDetailJSON struct {
+	tournamentJSON
+	Matches []tournamentMatchJSON `json:"matches"`
+}
+
+type tournamentMatchJSON struct {
+	Round       int    `json:"round"`
+	Player1     string `json:"player1"`
+	Player2     string `json:"player2"`
+	Player1Wins int    `json:"player1_wins"`
+	Player2Wins int    `json:"player2_wins"`
+}
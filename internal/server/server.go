@@ -0,0 +1,713 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/melee-elo-ranking/internal/config"
+	"github.com/melee-elo-ranking/internal/generator"
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+const (
+	defaultPerPage = 50
+	maxPerPage     = 500
+)
+
+// Server serves the rankings straight from the database, as HTML pages
+// rendered with the generator templates and as JSON under /api/.
+type Server struct {
+	store    *storage.Storage
+	cfg      *config.Config
+	ingester Ingester
+	mux      *http.ServeMux
+}
+
+// New returns a server reading from store. Titles and the ranking threshold
+// come from the main profile of cfg. Submitted tournaments are passed to
+// ingester; with a nil ingester the server is read-only.
+func New(store *storage.Storage, cfg *config.Config, ingester Ingester) *Server {
+	s := &Server{
+		store:    store,
+		cfg:      cfg,
+		ingester: ingester,
+		mux:      http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/", s.handleIndex)
+	s.mux.HandleFunc("/players/", s.handlePlayerPage)
+	s.mux.HandleFunc("/matchups.html", s.handleMatchupPage)
+	s.mux.HandleFunc("/tournaments/", s.handleTournamentPage)
+	s.mux.HandleFunc("/h2h/", s.handleHeadToHeadPage)
+	s.mux.HandleFunc("/records.html", s.handleRecordsPage)
+	s.mux.HandleFunc("/regions/", s.handleLeaderboardPage)
+	s.mux.HandleFunc("/clubs/", s.handleLeaderboardPage)
+	s.mux.HandleFunc("/api/rankings", s.handleRankings)
+	s.mux.HandleFunc("/api/players/", s.handlePlayer)
+	s.mux.HandleFunc("/api/matchups", s.handleMatchups)
+	s.mux.HandleFunc("/api/tournaments", s.handleTournaments)
+	s.mux.HandleFunc("/api/tournaments/", s.handleTournament)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost && r.URL.Path == "/api/tournaments" {
+		s.handleUpload(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// rankings returns the ranked players along with the slugs of every player.
+func (s *Server) rankings() ([]storage.Ranking, *generator.Slugs, error) {
+	players, err := s.store.GetAllPlayers()
+	if err != nil {
+		return nil, nil, err
+	}
+	return storage.RankPlayers(players, s.cfg.Output.MinMatches), generator.PlayerSlugs(players), nil
+}
+
+// generator returns a generator for the main profile. Slugs change as
+// players join, so one is made per request.
+func (s *Server) generator(slugs *generator.Slugs) *generator.Generator {
+	gen := generator.New(s.cfg.Output.Title, s.cfg.Output.Description)
+	gen.SetSlugs(slugs)
+	gen.SetMatchupMinSets(s.cfg.Output.MatchupMinSets)
+	return gen
+}
+
+// leaderboardGenerator returns a generator that links the region and club
+// leaderboards.
+func (s *Server) leaderboardGenerator(slugs *generator.Slugs) (*generator.Generator, error) {
+	regions, err := s.store.GetGroups(storage.GroupRegion)
+	if err != nil {
+		return nil, err
+	}
+	clubs, err := s.store.GetGroups(storage.GroupTeam)
+	if err != nil {
+		return nil, err
+	}
+	gen := s.generator(slugs)
+	gen.SetLeaderboards(regions, clubs)
+	return gen, nil
+}
+
+// comparingGenerator returns a generator whose player charts can be
+// compared with the field and the ranked players.
+func (s *Server) comparingGenerator(slugs *generator.Slugs) (*generator.Generator, error) {
+	players, err := s.store.GetAllPlayers()
+	if err != nil {
+		return nil, err
+	}
+	dated, err := s.store.GetDatedMatches()
+	if err != nil {
+		return nil, err
+	}
+	var ranked []string
+	for _, r := range storage.RankPlayers(players, s.cfg.Output.MinMatches) {
+		ranked = append(ranked, r.DisplayName)
+	}
+	gen := s.generator(slugs)
+	gen.SetRatingHistory(dated, ranked)
+	return gen, nil
+}
+
+// lookupPlayer returns a player and their ranking, with a zero rank if they
+// are not ranked. key is the player's slug or display name. It returns nil
+// if there is no such player.
+func (s *Server) lookupPlayer(key string) (*storage.Ranking, *generator.Slugs, error) {
+	players, err := s.store.GetAllPlayers()
+	if err != nil {
+		return nil, nil, err
+	}
+	slugs := generator.PlayerSlugs(players)
+	name := key
+	if n, ok := slugs.Name(key); ok {
+		name = n
+	}
+	for _, r := range storage.RankPlayers(players, s.cfg.Output.MinMatches) {
+		if r.DisplayName == name {
+			return &r, slugs, nil
+		}
+	}
+	for _, p := range players {
+		if p.DisplayName != name {
+			continue
+		}
+		r := storage.Ranking{
+			PlayerID:      p.ID,
+			DisplayName:   p.DisplayName,
+			Username:      p.Username,
+			CurrentELO:    p.CurrentELO,
+			MatchesPlayed: p.MatchesPlayed,
+			Wins:          p.Wins,
+			Losses:        p.Losses,
+		}
+		if p.MatchesPlayed > 0 {
+			r.WinRate = float64(p.Wins) / float64(p.MatchesPlayed) * 100
+		}
+		return &r, slugs, nil
+	}
+	return nil, slugs, nil
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" && r.URL.Path != "/index.html" {
+		http.NotFound(w, r)
+		return
+	}
+	rankings, slugs, err := s.rankings()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	gen, err := s.leaderboardGenerator(slugs)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	last, err := s.store.GetLastRatedTournament()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if last != nil {
+		previous, err := s.store.GetRankingSnapshotBefore(last.Date)
+		if err != nil {
+			internalError(w, err)
+			return
+		}
+		gen.SetPreviousRanking(previous)
+	}
+	page, err := gen.RenderIndex(rankings, "index.html")
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	respond(w, r, "text/html; charset=utf-8", page)
+}
+
+func (s *Server) handlePlayerPage(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/players/"), ".html")
+	player, slugs, err := s.lookupPlayer(key)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if player == nil {
+		http.NotFound(w, r)
+		return
+	}
+	// Pages used to be named after the display name
+	if slug := slugs.Slug(player.DisplayName); key != slug {
+		http.Redirect(w, r, slug+".html", http.StatusMovedPermanently)
+		return
+	}
+	history, err := s.store.GetPlayerMatchHistory(player.DisplayName)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	gen, err := s.comparingGenerator(slugs)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	rankHistory, err := s.store.GetRankHistory(player.PlayerID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	gen.SetRankHistory(rankHistory)
+	performances, err := s.store.GetPlayerPerformances(player.DisplayName)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	gen.SetPerformances(performances)
+	profiles, err := s.store.GetPlayerProfiles()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	gen.SetPlayerProfiles(profiles)
+	page, err := gen.RenderPlayerPage(player.DisplayName, history, *player)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	respond(w, r, "text/html; charset=utf-8", page)
+}
+
+func (s *Server) handleMatchupPage(w http.ResponseWriter, r *http.Request) {
+	rankings, slugs, err := s.rankings()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	matchups, err := s.store.GetMatchups()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	names := make([]string, len(rankings))
+	for i, r := range rankings {
+		names[i] = r.DisplayName
+	}
+	page, err := s.generator(slugs).RenderMatchupMatrix(matchups, names)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	respond(w, r, "text/html; charset=utf-8", page)
+}
+
+func (s *Server) handleTournamentPage(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/tournaments/")
+	players, err := s.store.GetAllPlayers()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	gen := s.generator(generator.PlayerSlugs(players))
+
+	if name == "" || name == "index.html" {
+		tournaments, err := s.store.GetAllTournaments()
+		if err != nil {
+			internalError(w, err)
+			return
+		}
+		page, err := gen.RenderTournamentList(tournaments)
+		if err != nil {
+			internalError(w, err)
+			return
+		}
+		respond(w, r, "text/html; charset=utf-8", page)
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimSuffix(name, ".html"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	tournament, err := s.store.GetTournamentByMeleeID(id)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if tournament == nil {
+		http.NotFound(w, r)
+		return
+	}
+	matches, err := s.store.GetTournamentResults(id)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	page, err := gen.RenderTournamentPage(*tournament, matches)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	respond(w, r, "text/html; charset=utf-8", page)
+}
+
+func (s *Server) handleHeadToHeadPage(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/h2h/"), ".html")
+	players, err := s.store.GetAllPlayers()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	slugs := generator.PlayerSlugs(players)
+
+	// Slugs may contain "-vs-" themselves, so try every split
+	var player1, player2 string
+	for i := 0; player1 == ""; {
+		j := strings.Index(name[i:], "-vs-")
+		if j < 0 {
+			break
+		}
+		j += i
+		a, aok := slugs.Name(name[:j])
+		b, bok := slugs.Name(name[j+len("-vs-"):])
+		if aok && bok {
+			player1, player2 = a, b
+		}
+		i = j + 1
+	}
+	if player1 == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	matches, err := s.store.GetDatedMatches()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	var sets []storage.TournamentMatch
+	for _, m := range matches {
+		if (m.Player1 == player1 && m.Player2 == player2) || (m.Player1 == player2 && m.Player2 == player1) {
+			sets = append(sets, m)
+		}
+	}
+	gen := s.generator(slugs)
+	pairs := gen.HeadToHeads(sets)
+	if len(pairs) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	page, err := gen.RenderHeadToHeadPage(pairs[0])
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	respond(w, r, "text/html; charset=utf-8", page)
+}
+
+func (s *Server) handleRecordsPage(w http.ResponseWriter, r *http.Request) {
+	players, err := s.store.GetAllPlayers()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	matches, err := s.store.GetDatedMatches()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	page, err := s.generator(generator.PlayerSlugs(players)).RenderRecords(matches)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	respond(w, r, "text/html; charset=utf-8", page)
+}
+
+// handleLeaderboardPage serves the leaderboards of regions and clubs, and
+// the club table at /clubs/.
+func (s *Server) handleLeaderboardPage(w http.ResponseWriter, r *http.Request) {
+	players, err := s.store.GetAllPlayers()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	gen, err := s.leaderboardGenerator(generator.PlayerSlugs(players))
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	if r.URL.Path == "/clubs/" || r.URL.Path == "/clubs/index.html" {
+		clubs, err := s.store.GetClubRankings(s.cfg.Output.ClubTopMembers, s.cfg.Output.MinMatches)
+		if err != nil {
+			internalError(w, err)
+			return
+		}
+		page, err := gen.RenderClubTable(clubs, s.cfg.Output.ClubTopMembers)
+		if err != nil {
+			internalError(w, err)
+			return
+		}
+		respond(w, r, "text/html; charset=utf-8", page)
+		return
+	}
+
+	group, prefix := storage.GroupRegion, "/regions/"
+	if strings.HasPrefix(r.URL.Path, "/clubs/") {
+		group, prefix = storage.GroupTeam, "/clubs/"
+	}
+	name, ok := gen.LeaderboardName(group, strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, prefix), ".html"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	rankings, err := s.store.GetGroupRankings(group, name, s.cfg.Output.MinMatches)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	page, err := gen.RenderLeaderboard(group, name, rankings)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	respond(w, r, "text/html; charset=utf-8", page)
+}
+
+func (s *Server) handleRankings(w http.ResponseWriter, r *http.Request) {
+	p, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	rankings, slugs, err := s.rankings()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	start, end := p.bounds(len(rankings))
+	items := make([]rankingJSON, 0, end-start)
+	for _, r := range rankings[start:end] {
+		items = append(items, newRankingJSON(r, slugs.Slug(r.DisplayName)))
+	}
+	writeJSON(w, r, pageJSON{Page: p.page, PerPage: p.perPage, Total: len(rankings), Items: items})
+}
+
+func (s *Server) handlePlayer(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/players/")
+	if name == "" {
+		writeError(w, http.StatusNotFound, "missing player name")
+		return
+	}
+	p, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	player, slugs, err := s.lookupPlayer(name)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if player == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no player named %q", name))
+		return
+	}
+	history, err := s.store.GetPlayerMatchHistory(player.DisplayName)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	start, end := p.bounds(len(history))
+	matches := make([]playerMatchJSON, 0, end-start)
+	for _, m := range history[start:end] {
+		matches = append(matches, newPlayerMatchJSON(m))
+	}
+	writeJSON(w, r, playerJSON{
+		rankingJSON: newRankingJSON(*player, slugs.Slug(player.DisplayName)),
+		History:     pageJSON{Page: p.page, PerPage: p.perPage, Total: len(history), Items: matches},
+	})
+}
+
+func (s *Server) handleMatchups(w http.ResponseWriter, r *http.Request) {
+	p, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	matchups, err := s.store.GetMatchups()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	if player := r.URL.Query().Get("player"); player != "" {
+		// Put the requested player on the left of every matchup
+		filtered := matchups[:0]
+		for _, m := range matchups {
+			switch player {
+			case m.Player1:
+				filtered = append(filtered, m)
+			case m.Player2:
+				filtered = append(filtered, m.Reverse())
+			}
+		}
+		matchups = filtered
+	}
+
+	start, end := p.bounds(len(matchups))
+	items := make([]matchupJSON, 0, end-start)
+	for _, m := range matchups[start:end] {
+		items = append(items, newMatchupJSON(m))
+	}
+	writeJSON(w, r, pageJSON{Page: p.page, PerPage: p.perPage, Total: len(matchups), Items: items})
+}
+
+func (s *Server) handleTournaments(w http.ResponseWriter, r *http.Request) {
+	p, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	tournaments, err := s.store.GetAllTournaments()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	// Newest first
+	for i, j := 0, len(tournaments)-1; i < j; i, j = i+1, j-1 {
+		tournaments[i], tournaments[j] = tournaments[j], tournaments[i]
+	}
+
+	start, end := p.bounds(len(tournaments))
+	items := make([]tournamentJSON, 0, end-start)
+	for _, t := range tournaments[start:end] {
+		items = append(items, newTournamentJSON(t))
+	}
+	writeJSON(w, r, pageJSON{Page: p.page, PerPage: p.perPage, Total: len(tournaments), Items: items})
+}
+
+func (s *Server) handleTournament(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/tournaments/"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "invalid tournament ID")
+		return
+	}
+	tournament, err := s.store.GetTournamentByMeleeID(id)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if tournament == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no tournament %d", id))
+		return
+	}
+
+	matches, err := s.store.GetTournamentMatches(id)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	players, err := s.store.GetAllPlayers()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	names := make(map[int64]string, len(players))
+	for _, p := range players {
+		names[p.ID] = p.DisplayName
+	}
+
+	detail := tournamentDetailJSON{tournamentJSON: newTournamentJSON(*tournament)}
+	detail.Matches = make([]tournamentMatchJSON, 0, len(matches))
+	for _, m := range matches {
+		detail.Matches = append(detail.Matches, tournamentMatchJSON{
+			Round:       m.Round,
+			Player1:     names[m.Player1ID],
+			Player2:     names[m.Player2ID],
+			Player1Wins: m.Player1Wins,
+			Player2Wins: m.Player2Wins,
+		})
+	}
+	writeJSON(w, r, detail)
+}
+
+// page is a requested slice of a list, from the page and per_page query
+// parameters.
+type page struct {
+	page    int
+	perPage int
+}
+
+func parsePage(r *http.Request) (page, error) {
+	p := page{page: 1, perPage: defaultPerPage}
+	q := r.URL.Query()
+	if v := q.Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return p, fmt.Errorf("invalid page %q", v)
+		}
+		p.page = n
+	}
+	if v := q.Get("per_page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPerPage {
+			return p, fmt.Errorf("invalid per_page %q, expected 1-%d", v, maxPerPage)
+		}
+		p.perPage = n
+	}
+	return p, nil
+}
+
+// bounds returns the slice indexes of the page in a list of n items.
+func (p page) bounds(n int) (int, int) {
+	start := (p.page - 1) * p.perPage
+	if start > n {
+		start = n
+	}
+	end := start + p.perPage
+	if end > n {
+		end = n
+	}
+	return start, end
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	respond(w, r, "application/json", body)
+}
+
+// respond writes body with an ETag and answers conditional requests whose
+// If-None-Match matches it with 304 Not Modified.
+func respond(w http.ResponseWriter, r *http.Request, contentType string, body []byte) {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(body)
+}
+
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// writeJSONStatus writes an uncached JSON response, e.g. to a POST.
+func writeJSONStatus(w http.ResponseWriter, status int, v interface{}) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		internalError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSONStatus(w, status, errorJSON{Error: message})
+}
+
+func internalError(w http.ResponseWriter, err error) {
+	log.Printf("Warning: request failed: %v", err)
+	writeError(w, http.StatusInternalServerError, "internal error")
+}
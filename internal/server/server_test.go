@@ -0,0 +1,296 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/melee-elo-ranking/internal/config"
+	"github.com/melee-elo-ranking/internal/storage"
+)
+
+// newTestServer returns a server over a database with three ranked players
+// and one unranked one.
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	store, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	store.GetOrCreateTournament(100, time.Date(2024, 8, 31, 0, 0, 0, 0, time.UTC))
+	store.UpdateTournamentMetadata(100, "Weekly #1", "weekly", "", "")
+
+	names := []string{"Alice", "Bob", "Carol", "Dave"}
+	ids := make([]int64, len(names))
+	for i, name := range names {
+		p, err := store.GetOrCreatePlayer(int64(i+1), name, strings.ToLower(name))
+		if err != nil {
+			t.Fatalf("failed to create player: %v", err)
+		}
+		ids[i] = p.ID
+	}
+
+	// Alice beats everyone, Bob beats Carol; Dave only plays once
+	results := [][2]int{{0, 1}, {0, 2}, {1, 2}}
+	for round := 1; round <= 4; round++ {
+		for i, r := range results {
+			store.SaveMatch(storage.Match{
+				ID: fmt.Sprintf("m-%d-%d", round, i), TournamentID: 100, Round: round,
+				Player1ID: ids[r[0]], Player2ID: ids[r[1]], Player1Wins: 2, Player2Wins: 0,
+			})
+			store.UpdatePlayerELO(ids[r[0]], 1500+10*(3-r[0]), true)
+			store.UpdatePlayerELO(ids[r[1]], 1500-10*r[1], false)
+		}
+	}
+	store.SaveMatch(storage.Match{
+		ID: "m-dave", TournamentID: 100, Round: 5,
+		Player1ID: ids[3], Player2ID: ids[0], Player1Wins: 0, Player2Wins: 2,
+	})
+	store.UpdatePlayerELO(ids[3], 1480, false)
+
+	cfg := &config.Config{
+		Output: config.OutputConfig{Title: "Test Rankings", MinMatches: 5},
+	}
+	return New(store, cfg, nil)
+}
+
+func get(t *testing.T, s *Server, target string, header ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRankingsPagination(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := get(t, s, "/api/rankings?page=2&per_page=2")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var page struct {
+		Page    int           `json:"page"`
+		PerPage int           `json:"per_page"`
+		Total   int           `json:"total"`
+		Items   []rankingJSON `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if page.Total != 3 || page.Page != 2 || len(page.Items) != 1 {
+		t.Fatalf("expected second page with 1 of 3 players, got %+v", page)
+	}
+	if page.Items[0].Rank != 3 || page.Items[0].DisplayName != "Carol" {
+		t.Errorf("expected Carol at rank 3, got %+v", page.Items[0])
+	}
+
+	if rec := get(t, s, "/api/rankings?per_page=0"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid per_page, got %d", rec.Code)
+	}
+}
+
+func TestETag(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := get(t, s, "/api/rankings")
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected ETag header")
+	}
+
+	rec = get(t, s, "/api/rankings", "If-None-Match", etag)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("expected empty 304, got %d with %d bytes", rec.Code, rec.Body.Len())
+	}
+
+	rec = get(t, s, "/api/rankings?page=2", "If-None-Match", etag)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for different content, got %d", rec.Code)
+	}
+}
+
+func TestPlayer(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := get(t, s, "/api/players/Dave")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var player struct {
+		rankingJSON
+		History struct {
+			Total int               `json:"total"`
+			Items []playerMatchJSON `json:"items"`
+		} `json:"history"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &player); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if player.Rank != 0 || player.DisplayName != "Dave" {
+		t.Errorf("expected unranked Dave, got %+v", player.rankingJSON)
+	}
+	if player.History.Total != 1 || player.History.Items[0].Opponent != "Alice" || player.History.Items[0].Result != "Loss" {
+		t.Errorf("unexpected history: %+v", player.History)
+	}
+
+	if rec := get(t, s, "/api/players/Nobody"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown player, got %d", rec.Code)
+	}
+}
+
+func TestTournaments(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := get(t, s, "/api/tournaments/100")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var detail tournamentDetailJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &detail); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if detail.Name != "Weekly #1" || detail.Date != "2024-08-31" || len(detail.Matches) != 13 {
+		t.Errorf("unexpected tournament: %+v", detail.tournamentJSON)
+	}
+
+	if rec := get(t, s, "/api/tournaments/999"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown tournament, got %d", rec.Code)
+	}
+}
+
+func TestMatchupsForPlayer(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := get(t, s, "/api/matchups?player=Bob")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Total int           `json:"total"`
+		Items []matchupJSON `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if body.Total != 2 {
+		t.Fatalf("expected Bob's 2 matchups, got %d", body.Total)
+	}
+	for _, m := range body.Items {
+		if m.Player1 != "Bob" {
+			t.Errorf("expected Bob on the left, got %+v", m)
+		}
+		if m.Player2 == "Alice" && (m.Player1Sets != 0 || m.Player2Sets != 4 || m.SetsPlayed != 4) {
+			t.Errorf("expected Bob to be 0-4 in sets against Alice, got %+v", m)
+		}
+	}
+}
+
+func TestHTMLPages(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := get(t, s, "/")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Test Rankings") {
+		t.Fatalf("expected rankings page, got %d", rec.Code)
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("expected HTML, got %s", rec.Header().Get("Content-Type"))
+	}
+
+	if !strings.Contains(rec.Body.String(), `href="players/alice.html"`) {
+		t.Error("expected rankings to link player slugs")
+	}
+
+	rec = get(t, s, "/players/alice.html")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Alice") {
+		t.Errorf("expected player page, got %d", rec.Code)
+	}
+	rec = get(t, s, "/players/Alice.html")
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/players/alice.html" {
+		t.Errorf("expected old path to redirect to the slug, got %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+
+	if rec := get(t, s, "/matchups.html"); rec.Code != http.StatusOK {
+		t.Errorf("expected matchup page, got %d", rec.Code)
+	}
+
+	rec = get(t, s, "/tournaments/")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `href="100.html"`) {
+		t.Errorf("expected tournament list, got %d", rec.Code)
+	}
+	rec = get(t, s, "/tournaments/100.html")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Weekly #1") {
+		t.Errorf("expected tournament page, got %d", rec.Code)
+	}
+	if rec := get(t, s, "/tournaments/999.html"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown tournament, got %d", rec.Code)
+	}
+
+	rec = get(t, s, "/matchups.html")
+	if !strings.Contains(rec.Body.String(), `href="h2h/alice-vs-bob.html"`) {
+		t.Error("expected matrix cells to link head-to-head pages")
+	}
+	rec = get(t, s, "/h2h/alice-vs-bob.html")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Alice") || !strings.Contains(rec.Body.String(), "4-0") {
+		t.Errorf("expected head-to-head page, got %d", rec.Code)
+	}
+	for _, path := range []string{"/h2h/alice-vs-nobody.html", "/h2h/bob-vs-dave.html"} {
+		if rec := get(t, s, path); rec.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for %s, got %d", path, rec.Code)
+		}
+	}
+	if rec := get(t, s, "/records.html"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Records") {
+		t.Errorf("expected records page, got %d", rec.Code)
+	}
+	if rec := get(t, s, "/missing"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestLeaderboardPages(t *testing.T) {
+	s := newTestServer(t)
+	for _, name := range []string{"Alice", "Bob"} {
+		player, _ := s.store.FindPlayer(name)
+		s.store.SavePlayerProfile(storage.PlayerProfile{PlayerID: player.ID, Region: "Paris", Team: "Les Bleus"})
+	}
+	s.cfg.Output.ClubTopMembers = 2
+
+	rec := get(t, s, "/")
+	if !strings.Contains(rec.Body.String(), `href="regions/paris.html"`) {
+		t.Error("expected the index to link the leaderboards")
+	}
+	rec = get(t, s, "/regions/paris.html")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `href="../players/bob.html"`) || strings.Contains(rec.Body.String(), "Carol") {
+		t.Errorf("expected Paris leaderboard, got %d", rec.Code)
+	}
+	rec = get(t, s, "/clubs/les-bleus.html")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Members of Les Bleus") {
+		t.Errorf("expected club leaderboard, got %d", rec.Code)
+	}
+	rec = get(t, s, "/clubs/")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `href="../clubs/les-bleus.html"`) {
+		t.Errorf("expected club table, got %d", rec.Code)
+	}
+	if rec := get(t, s, "/regions/lyon.html"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown region, got %d", rec.Code)
+	}
+}
+
+func TestRejectsWrites(t *testing.T) {
+	s := newTestServer(t)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/rankings", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/melee-elo-ranking/internal/impact"
+	"github.com/melee-elo-ranking/internal/parser"
+)
+
+// maxUploadBytes caps the size of a submitted export.
+const maxUploadBytes = 10 << 20
+
+var (
+	// ErrDuplicate is returned by an Ingester for an export that was already
+	// ingested byte for byte.
+	ErrDuplicate = errors.New("export was already ingested")
+	// ErrNeedsDate is returned by an Ingester when the tournament date is
+	// unknown and the export was queued until it is set.
+	ErrNeedsDate = errors.New("tournament date is unknown")
+)
+
+// Ingester stores a submitted melee export and brings ratings up to date,
+// returning how player standings moved. date is empty or YYYY-MM-DD.
+type Ingester interface {
+	IngestUpload(tournamentID int, date string, data []byte) (impact.Report, error)
+}
+
+type uploadJSON struct {
+	Status       string         `json:"status"`
+	TournamentID int            `json:"tournament_id"`
+	Matches      int            `json:"matches"`
+	Impact       *impact.Report `json:"impact,omitempty"`
+}
+
+// handleUpload accepts a melee export as the request body of
+// POST /api/tournaments?id=<melee id>[&date=YYYY-MM-DD].
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	if s.ingester == nil || s.cfg.Server.UploadToken == "" {
+		writeError(w, http.StatusForbidden, "uploads are disabled")
+		return
+	}
+	if !authorized(r, s.cfg.Server.UploadToken) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="elo-cli"`)
+		writeError(w, http.StatusUnauthorized, "invalid or missing token")
+		return
+	}
+
+	q := r.URL.Query()
+	id, err := strconv.Atoi(q.Get("id"))
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "id must be a melee tournament ID")
+		return
+	}
+	date := q.Get("date")
+	if date != "" {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid date %q, expected YYYY-MM-DD", date))
+			return
+		}
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxUploadBytes))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("export is larger than %d bytes", maxUploadBytes))
+		return
+	}
+	matches, err := parser.New().ParseBytes(data, id)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid export: %v", err))
+		return
+	}
+	if len(matches) == 0 {
+		writeError(w, http.StatusBadRequest, "export contains no matches")
+		return
+	}
+
+	result := uploadJSON{TournamentID: id, Matches: len(matches)}
+	report, err := s.ingester.IngestUpload(id, date, data)
+	switch {
+	case errors.Is(err, ErrDuplicate):
+		result.Status = "duplicate"
+		writeJSONStatus(w, http.StatusOK, result)
+	case errors.Is(err, ErrNeedsDate):
+		result.Status = "needs_date"
+		writeJSONStatus(w, http.StatusAccepted, result)
+	case err != nil:
+		internalError(w, err)
+	default:
+		result.Status = "ingested"
+		result.Impact = &report
+		writeJSONStatus(w, http.StatusCreated, result)
+	}
+}
+
+// authorized reports whether the request carries token as a bearer token.
+func authorized(r *http.Request, token string) bool {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+	given := strings.TrimPrefix(header, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
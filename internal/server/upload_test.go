@@ -0,0 +1,122 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/melee-elo-ranking/internal/impact"
+)
+
+const testExport = `[{"RoundNumber": 1, "Team1Id": 10, "Team1": "Alice", "Team1WinsAndByes": 2,
+	"Team2Id": 20, "Team2": "Bob", "Team2WinsAndByes": 0, "HasResult": true}]`
+
+type fakeIngester struct {
+	calls  int
+	id     int
+	date   string
+	report impact.Report
+	err    error
+}
+
+func (f *fakeIngester) IngestUpload(tournamentID int, date string, data []byte) (impact.Report, error) {
+	f.calls++
+	f.id, f.date = tournamentID, date
+	return f.report, f.err
+}
+
+func newUploadServer(t *testing.T, ingester *fakeIngester) *Server {
+	t.Helper()
+	s := newTestServer(t)
+	s.cfg.Server.UploadToken = "secret"
+	s.ingester = ingester
+	return s
+}
+
+func post(s *Server, target, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestUpload(t *testing.T) {
+	ingester := &fakeIngester{report: impact.Report{Changes: []impact.Change{
+		{DisplayName: "Alice", ELOBefore: 1500, ELOAfter: 1520},
+	}}}
+	s := newUploadServer(t, ingester)
+
+	rec := post(s, "/api/tournaments?id=170676&date=2024-08-31", "secret", testExport)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var result uploadJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if result.Status != "ingested" || result.Matches != 1 || result.Impact == nil || len(result.Impact.Changes) != 1 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if ingester.id != 170676 || ingester.date != "2024-08-31" {
+		t.Errorf("expected ingester to get tournament and date, got %d %q", ingester.id, ingester.date)
+	}
+}
+
+func TestUploadRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		token  string
+		body   string
+		status int
+	}{
+		{"missing token", "/api/tournaments?id=1", "", testExport, http.StatusUnauthorized},
+		{"wrong token", "/api/tournaments?id=1", "guess", testExport, http.StatusUnauthorized},
+		{"missing id", "/api/tournaments", "secret", testExport, http.StatusBadRequest},
+		{"invalid date", "/api/tournaments?id=1&date=31.08.2024", "secret", testExport, http.StatusBadRequest},
+		{"invalid export", "/api/tournaments?id=1", "secret", "not json", http.StatusBadRequest},
+		{"no matches", "/api/tournaments?id=1", "secret", "[]", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ingester := &fakeIngester{}
+			rec := post(newUploadServer(t, ingester), tt.target, tt.token, tt.body)
+			if rec.Code != tt.status {
+				t.Errorf("expected %d, got %d: %s", tt.status, rec.Code, rec.Body)
+			}
+			if ingester.calls != 0 {
+				t.Error("expected rejected upload not to reach the ingester")
+			}
+		})
+	}
+}
+
+func TestUploadDisabledWithoutToken(t *testing.T) {
+	s := newTestServer(t)
+	s.ingester = &fakeIngester{}
+	if rec := post(s, "/api/tournaments?id=1", "", testExport); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", rec.Code)
+	}
+}
+
+func TestUploadOutcomes(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		want   string
+	}{
+		{ErrDuplicate, http.StatusOK, "duplicate"},
+		{ErrNeedsDate, http.StatusAccepted, "needs_date"},
+	}
+	for _, tt := range tests {
+		rec := post(newUploadServer(t, &fakeIngester{err: tt.err}), "/api/tournaments?id=1", "secret", testExport)
+		if rec.Code != tt.status || !strings.Contains(rec.Body.String(), `"status":"`+tt.want+`"`) {
+			t.Errorf("expected %d %s, got %d: %s", tt.status, tt.want, rec.Code, rec.Body)
+		}
+	}
+}
@@ -1,11 +1,17 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/melee-elo-ranking/internal/elo"
 )
 
 type Storage struct {
@@ -32,6 +38,8 @@ type Match struct {
 	Round             int
 	Player1ID         int64
 	Player2ID         int64
+	Player1ExternalID int64
+	Player2ExternalID int64
 	Player1Wins       int
 	Player2Wins       int
 	DatePlayed        time.Time
@@ -42,12 +50,33 @@ type Match struct {
 }
 
 type Tournament struct {
-	ID      int64
-	MeleeID int
-	Date    time.Time
+	ID             int64
+	MeleeID        int
+	Date           time.Time
+	ContentHash    string
+	SourceFilename string
+	Rated          bool
+	Name           string
+	Tier           string
+	Location       string
+	Organizer      string
+}
+
+// UndatedSortDate is where tournaments without a date are ordered, matching
+// the COALESCE(date, '1970-01-01') every sorted query uses.
+var UndatedSortDate = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// SortDate returns the date the tournament is replayed at: its date, or
+// UndatedSortDate without one.
+func (t Tournament) SortDate() time.Time {
+	if t.Date.IsZero() {
+		return UndatedSortDate
+	}
+	return t.Date
 }
 
 type Ranking struct {
+	PlayerID      int64
 	Rank          int
 	DisplayName   string
 	Username      string
@@ -58,13 +87,19 @@ type Ranking struct {
 	WinRate       float64
 }
 
+// Matchup is the record of Player1 against Player2. Sets are the primary
+// figure; game totals can favour a player who lost most of their sets.
 type Matchup struct {
-	Player1        string
-	Player2        string
-	Player1Wins    int
-	Player2Wins    int
-	GamesPlayed    int
-	Player1WinRate float64
+	Player1           string
+	Player2           string
+	Player1Sets       int
+	Player2Sets       int
+	SetsPlayed        int
+	Player1SetWinRate float64
+	Player1Wins       int
+	Player2Wins       int
+	GamesPlayed       int
+	Player1WinRate    float64
 }
 
 func New(dbPath string) (*Storage, error) {
@@ -94,6 +129,63 @@ func (s *Storage) Close() error {
 	return s.db.Close()
 }
 
+// CopyToMemory returns a new Storage holding an in-memory copy of the
+// database. Writes to the copy never reach the original file.
+func (s *Storage) CopyToMemory() (*Storage, error) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	// Every connection to ":memory:" is a separate database, so keep the
+	// pool at a single connection that is never recycled.
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxLifetime(0)
+
+	ctx := context.Background()
+	dstConn, err := db.Conn(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	defer dstConn.Close()
+
+	srcConn, err := s.db.Conn(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	defer srcConn.Close()
+
+	err = dstConn.Raw(func(dst interface{}) error {
+		return srcConn.Raw(func(src interface{}) error {
+			dstSQLite, ok := dst.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected destination connection type %T", dst)
+			}
+			srcSQLite, ok := src.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected source connection type %T", src)
+			}
+
+			backup, err := dstSQLite.Backup("main", srcSQLite, "main")
+			if err != nil {
+				return err
+			}
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to copy database: %w", err)
+	}
+
+	return &Storage{db: db}, nil
+}
+
 func (s *Storage) createTables() error {
 	queries := []string{
 		`CREATE TABLE IF NOT EXISTS players (
@@ -112,6 +204,13 @@ func (s *Storage) createTables() error {
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			melee_id INTEGER UNIQUE NOT NULL,
 			date DATETIME,
+			content_hash TEXT,
+			source_filename TEXT,
+			rated INTEGER DEFAULT 0,
+			name TEXT,
+			tier TEXT,
+			location TEXT,
+			organizer TEXT,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)`,
 		`CREATE TABLE IF NOT EXISTS matches (
@@ -132,6 +231,36 @@ func (s *Storage) createTables() error {
 			FOREIGN KEY (player1_id) REFERENCES players(id),
 			FOREIGN KEY (player2_id) REFERENCES players(id)
 		)`,
+		`CREATE TABLE IF NOT EXISTS ranking_snapshots (
+			snapshot_date DATETIME NOT NULL,
+			player_id INTEGER NOT NULL,
+			rank INTEGER NOT NULL,
+			elo INTEGER NOT NULL,
+			PRIMARY KEY (snapshot_date, player_id),
+			FOREIGN KEY (player_id) REFERENCES players(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS rating_history (
+			player_id INTEGER NOT NULL,
+			tournament_id INTEGER NOT NULL,
+			date DATETIME,
+			elo INTEGER NOT NULL,
+			matches_played INTEGER NOT NULL,
+			wins INTEGER NOT NULL,
+			losses INTEGER NOT NULL,
+			PRIMARY KEY (player_id, tournament_id),
+			FOREIGN KEY (player_id) REFERENCES players(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS player_profiles (
+			player_id INTEGER PRIMARY KEY,
+			region TEXT,
+			team TEXT,
+			main TEXT,
+			links TEXT,
+			opt_out INTEGER NOT NULL DEFAULT 0,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (player_id) REFERENCES players(id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_rating_history_date ON rating_history(date)`,
 		`CREATE INDEX IF NOT EXISTS idx_matches_tournament ON matches(tournament_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_matches_date ON matches(date_played)`,
 	}
@@ -142,9 +271,104 @@ func (s *Storage) createTables() error {
 		}
 	}
 
+	if err := s.addMissingColumns(); err != nil {
+		return err
+	}
+	return s.createViews()
+}
+
+// createViews (re)creates public_players, the players as they may be
+// published. Every query behind a page, the JSON API or the rankings reads
+// players through it, so an opt-out is applied there and nowhere else:
+// the player keeps their rating but loses their name, username and profile.
+// Views hold no data, so they are dropped and recreated on every start to
+// pick up changes to their definition.
+func (s *Storage) createViews() error {
+	queries := []string{
+		`DROP VIEW IF EXISTS public_players`,
+		`CREATE VIEW public_players AS
+		SELECT p.id, p.external_id,
+		       CASE WHEN pp.opt_out THEN 'Anonymous #' || p.id ELSE p.display_name END AS display_name,
+		       CASE WHEN pp.opt_out THEN '' ELSE p.username END AS username,
+		       p.current_elo, p.matches_played, p.wins, p.losses, p.created_at, p.updated_at,
+		       CASE WHEN pp.opt_out THEN '' ELSE COALESCE(pp.region, '') END AS region,
+		       CASE WHEN pp.opt_out THEN '' ELSE COALESCE(pp.team, '') END AS team,
+		       CASE WHEN pp.opt_out THEN '' ELSE COALESCE(pp.main, '') END AS main,
+		       CASE WHEN pp.opt_out THEN '' ELSE COALESCE(pp.links, '') END AS links
+		FROM players p
+		LEFT JOIN player_profiles pp ON pp.player_id = p.id`,
+	}
+	for _, query := range queries {
+		if _, err := s.db.Exec(query); err != nil {
+			return fmt.Errorf("failed to create view: %w", err)
+		}
+	}
 	return nil
 }
 
+// addMissingColumns adds columns introduced after a table was first created,
+// so databases from older versions keep working.
+func (s *Storage) addMissingColumns() error {
+	columns := []struct {
+		table      string
+		name       string
+		definition string
+	}{
+		{"tournaments", "content_hash", "TEXT"},
+		{"tournaments", "source_filename", "TEXT"},
+		{"tournaments", "rated", "INTEGER DEFAULT 0"},
+		{"tournaments", "name", "TEXT"},
+		{"tournaments", "tier", "TEXT"},
+		{"tournaments", "location", "TEXT"},
+		{"tournaments", "organizer", "TEXT"},
+	}
+
+	for _, c := range columns {
+		exists, err := s.columnExists(c.table, c.name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.name, c.definition)); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", c.table, c.name, err)
+		}
+	}
+
+	if _, err := s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_tournaments_content_hash ON tournaments(content_hash)`); err != nil {
+		return fmt.Errorf("failed to create index: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) columnExists(table, column string) (bool, error) {
+	rows, err := s.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			colType    string
+			notNull    int
+			defaultVal sql.NullString
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
 func (s *Storage) migrateExistingData() error {
 	// Check if we already have tournaments
 	var count int
@@ -228,7 +452,7 @@ func (s *Storage) GetOrCreatePlayer(externalID int64, displayName, username stri
 func (s *Storage) GetPlayerByID(id int64) (*Player, error) {
 	var player Player
 	err := s.db.QueryRow(
-		"SELECT id, external_id, display_name, username, current_elo, matches_played, wins, losses, created_at, updated_at FROM players WHERE id = ?",
+		"SELECT id, external_id, display_name, username, current_elo, matches_played, wins, losses, created_at, updated_at FROM public_players WHERE id = ?",
 		id,
 	).Scan(&player.ID, &player.ExternalID, &player.DisplayName, &player.Username, &player.CurrentELO, &player.MatchesPlayed, &player.Wins, &player.Losses, &player.CreatedAt, &player.UpdatedAt)
 
@@ -239,6 +463,147 @@ func (s *Storage) GetPlayerByID(id int64) (*Player, error) {
 	return &player, nil
 }
 
+// GetAllPlayers returns every player regardless of how many matches they
+// have played, ordered by ID.
+func (s *Storage) GetAllPlayers() ([]Player, error) {
+	return s.queryPlayers("")
+}
+
+func (s *Storage) queryPlayers(where string, args ...interface{}) ([]Player, error) {
+	rows, err := s.db.Query(
+		"SELECT id, external_id, display_name, username, current_elo, matches_played, wins, losses, created_at, updated_at FROM public_players "+where+" ORDER BY id",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var players []Player
+	for rows.Next() {
+		var player Player
+		var username sql.NullString
+		err := rows.Scan(&player.ID, &player.ExternalID, &player.DisplayName, &username, &player.CurrentELO, &player.MatchesPlayed, &player.Wins, &player.Losses, &player.CreatedAt, &player.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		player.Username = username.String
+		players = append(players, player)
+	}
+
+	return players, rows.Err()
+}
+
+// FindPlayer looks a player up by their real display name, ignoring case
+// when there is no exact match. It is meant for administration and is the
+// only lookup that sees the names of opted-out players. It returns nil if
+// nobody has the name.
+func (s *Storage) FindPlayer(displayName string) (*Player, error) {
+	var player Player
+	var username sql.NullString
+	err := s.db.QueryRow(`
+		SELECT id, external_id, display_name, username, current_elo, matches_played, wins, losses, created_at, updated_at
+		FROM players
+		WHERE display_name = ? COLLATE NOCASE
+		ORDER BY display_name = ? DESC, id ASC
+		LIMIT 1`, displayName, displayName,
+	).Scan(&player.ID, &player.ExternalID, &player.DisplayName, &username, &player.CurrentELO, &player.MatchesPlayed, &player.Wins, &player.Losses, &player.CreatedAt, &player.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	player.Username = username.String
+	return &player, nil
+}
+
+// PlayerProfile is what a player chose to share about themselves. Main is
+// the character they play most. OptOut keeps their name and profile off
+// everything that is published; see createViews.
+type PlayerProfile struct {
+	PlayerID    int64
+	DisplayName string
+	Region      string
+	Team        string
+	Main        string
+	Links       []string
+	OptOut      bool
+}
+
+// GetPlayerProfile returns the stored profile of a player, including the
+// opt-out flag, or nil if they have none.
+func (s *Storage) GetPlayerProfile(playerID int64) (*PlayerProfile, error) {
+	var profile PlayerProfile
+	var region, team, main, links sql.NullString
+	err := s.db.QueryRow(`
+		SELECT pp.player_id, p.display_name, pp.region, pp.team, pp.main, pp.links, pp.opt_out
+		FROM player_profiles pp
+		JOIN players p ON pp.player_id = p.id
+		WHERE pp.player_id = ?`, playerID,
+	).Scan(&profile.PlayerID, &profile.DisplayName, &region, &team, &main, &links, &profile.OptOut)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	profile.Region, profile.Team, profile.Main = region.String, team.String, main.String
+	profile.Links = splitLinks(links.String)
+	return &profile, nil
+}
+
+// SavePlayerProfile creates or replaces a player's profile.
+func (s *Storage) SavePlayerProfile(profile PlayerProfile) error {
+	_, err := s.db.Exec(`
+		INSERT INTO player_profiles (player_id, region, team, main, links, opt_out, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(player_id) DO UPDATE SET
+			region = excluded.region,
+			team = excluded.team,
+			main = excluded.main,
+			links = excluded.links,
+			opt_out = excluded.opt_out,
+			updated_at = excluded.updated_at`,
+		profile.PlayerID, profile.Region, profile.Team, profile.Main,
+		strings.Join(profile.Links, "\n"), profile.OptOut)
+	return err
+}
+
+// GetPlayerProfiles returns the published profiles, by display name. Players
+// who opted out or shared nothing are left out.
+func (s *Storage) GetPlayerProfiles() ([]PlayerProfile, error) {
+	rows, err := s.db.Query(`
+		SELECT id, display_name, region, team, main, links
+		FROM public_players
+		WHERE region != '' OR team != '' OR main != '' OR links != ''
+		ORDER BY display_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []PlayerProfile
+	for rows.Next() {
+		var p PlayerProfile
+		var links string
+		if err := rows.Scan(&p.PlayerID, &p.DisplayName, &p.Region, &p.Team, &p.Main, &links); err != nil {
+			return nil, err
+		}
+		p.Links = splitLinks(links)
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+// splitLinks reads the links of a profile, which are stored one per line.
+func splitLinks(links string) []string {
+	if links == "" {
+		return nil
+	}
+	return strings.Split(links, "\n")
+}
+
 func (s *Storage) UpdatePlayerELO(playerID int64, newELO int, won bool) error {
 	query := `UPDATE players 
 			  SET current_elo = ?, 
@@ -304,13 +669,77 @@ func (s *Storage) GetOrCreateTournament(meleeID int, date time.Time) (*Tournamen
 	}, nil
 }
 
+// tournamentColumns are the columns read by scanTournament.
+const tournamentColumns = `id, melee_id, date, content_hash, source_filename, rated, name, tier, location, organizer`
+
+// scanTournament reads a row selected with tournamentColumns.
+func scanTournament(row interface{ Scan(...interface{}) error }) (Tournament, error) {
+	var t Tournament
+	var datePtr *time.Time
+	var contentHash, sourceFilename, name, tier, location, organizer sql.NullString
+	var rated sql.NullBool
+	err := row.Scan(&t.ID, &t.MeleeID, &datePtr, &contentHash, &sourceFilename, &rated, &name, &tier, &location, &organizer)
+	if err != nil {
+		return t, err
+	}
+	if datePtr != nil {
+		t.Date = *datePtr
+	}
+	t.ContentHash = contentHash.String
+	t.SourceFilename = sourceFilename.String
+	t.Rated = rated.Bool
+	t.Name = name.String
+	t.Tier = tier.String
+	t.Location = location.String
+	t.Organizer = organizer.String
+	return t, nil
+}
+
 func (s *Storage) GetTournamentByMeleeID(meleeID int) (*Tournament, error) {
+	t, err := scanTournament(s.db.QueryRow(
+		`SELECT `+tournamentColumns+` FROM tournaments WHERE melee_id = ?`,
+		meleeID,
+	))
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetAllTournaments returns every tournament in chronological order.
+func (s *Storage) GetAllTournaments() ([]Tournament, error) {
+	rows, err := s.db.Query(`SELECT ` + tournamentColumns + ` FROM tournaments
+		ORDER BY COALESCE(date, '1970-01-01') ASC, melee_id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tournaments []Tournament
+	for rows.Next() {
+		t, err := scanTournament(rows)
+		if err != nil {
+			return nil, err
+		}
+		tournaments = append(tournaments, t)
+	}
+	return tournaments, rows.Err()
+}
+
+// GetTournamentByContentHash returns the tournament that was ingested from a
+// file with the given content hash, or nil if there is none.
+func (s *Storage) GetTournamentByContentHash(hash string) (*Tournament, error) {
 	var t Tournament
 	var datePtr *time.Time
+	var contentHash, sourceFilename sql.NullString
 	err := s.db.QueryRow(
-		"SELECT id, melee_id, date FROM tournaments WHERE melee_id = ?",
-		meleeID,
-	).Scan(&t.ID, &t.MeleeID, &datePtr)
+		"SELECT id, melee_id, date, content_hash, source_filename FROM tournaments WHERE content_hash = ?",
+		hash,
+	).Scan(&t.ID, &t.MeleeID, &datePtr, &contentHash, &sourceFilename)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -321,9 +750,21 @@ func (s *Storage) GetTournamentByMeleeID(meleeID int) (*Tournament, error) {
 	if datePtr != nil {
 		t.Date = *datePtr
 	}
+	t.ContentHash = contentHash.String
+	t.SourceFilename = sourceFilename.String
 	return &t, nil
 }
 
+// UpdateTournamentSource records the content hash and filename of the file a
+// tournament was last ingested from.
+func (s *Storage) UpdateTournamentSource(meleeID int, contentHash, sourceFilename string) error {
+	_, err := s.db.Exec(
+		"UPDATE tournaments SET content_hash = ?, source_filename = ? WHERE melee_id = ?",
+		contentHash, sourceFilename, meleeID,
+	)
+	return err
+}
+
 func (s *Storage) GetTournamentsWithMissingDates() ([]Tournament, error) {
 	rows, err := s.db.Query("SELECT id, melee_id, date FROM tournaments WHERE date IS NULL")
 	if err != nil {
@@ -346,8 +787,93 @@ func (s *Storage) GetTournamentsWithMissingDates() ([]Tournament, error) {
 	return tournaments, rows.Err()
 }
 
+// UpdateTournamentDate stores the date of a tournament. Moving a rated
+// tournament changes the order matches are replayed in, so every tournament
+// is marked unrated and the next rating update replays them all, even from
+// another process.
 func (s *Storage) UpdateTournamentDate(meleeID int, date time.Time) error {
-	_, err := s.db.Exec("UPDATE tournaments SET date = ? WHERE melee_id = ?", date, meleeID)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var rated sql.NullBool
+	var datePtr *time.Time
+	err = tx.QueryRow("SELECT rated, date FROM tournaments WHERE melee_id = ?", meleeID).Scan(&rated, &datePtr)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if rated.Bool && (datePtr == nil || !datePtr.Equal(date)) {
+		if _, err := tx.Exec("UPDATE tournaments SET rated = 0"); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec("UPDATE tournaments SET date = ? WHERE melee_id = ?", date, meleeID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetLastRatedTournament returns the chronologically last tournament whose
+// matches are already reflected in player ratings, or nil if there is none.
+// Tournaments are ordered the same way as GetAllMatchesSorted orders matches.
+func (s *Storage) GetLastRatedTournament() (*Tournament, error) {
+	var t Tournament
+	var datePtr *time.Time
+	err := s.db.QueryRow(`
+		SELECT id, melee_id, date FROM tournaments
+		WHERE rated = 1
+		ORDER BY COALESCE(date, '1970-01-01') DESC, melee_id DESC
+		LIMIT 1
+	`).Scan(&t.ID, &t.MeleeID, &datePtr)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if datePtr != nil {
+		t.Date = *datePtr
+	}
+	return &t, nil
+}
+
+// GetUnratedTournaments returns the tournaments whose matches have not been
+// applied to player ratings yet, in chronological order.
+func (s *Storage) GetUnratedTournaments() ([]Tournament, error) {
+	rows, err := s.db.Query(`
+		SELECT id, melee_id, date FROM tournaments
+		WHERE rated = 0 OR rated IS NULL
+		ORDER BY COALESCE(date, '1970-01-01') ASC, melee_id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tournaments []Tournament
+	for rows.Next() {
+		var t Tournament
+		var datePtr *time.Time
+		if err := rows.Scan(&t.ID, &t.MeleeID, &datePtr); err != nil {
+			return nil, err
+		}
+		if datePtr != nil {
+			t.Date = *datePtr
+		}
+		tournaments = append(tournaments, t)
+	}
+	return tournaments, rows.Err()
+}
+
+// UpdateTournamentMetadata stores the descriptive fields of a tournament.
+func (s *Storage) UpdateTournamentMetadata(meleeID int, name, tier, location, organizer string) error {
+	_, err := s.db.Exec(
+		"UPDATE tournaments SET name = ?, tier = ?, location = ?, organizer = ? WHERE melee_id = ?",
+		name, tier, location, organizer, meleeID,
+	)
 	return err
 }
 
@@ -357,12 +883,23 @@ func (s *Storage) ResetAllPlayersELO() error {
 }
 
 func (s *Storage) GetAllMatchesSorted() ([]Match, error) {
+	return s.getMatchesSorted("")
+}
+
+// GetUnratedMatchesSorted returns the matches of tournaments that have not
+// been applied to ratings yet, in the same order as GetAllMatchesSorted.
+func (s *Storage) GetUnratedMatchesSorted() ([]Match, error) {
+	return s.getMatchesSorted("WHERE t.rated = 0 OR t.rated IS NULL")
+}
+
+func (s *Storage) getMatchesSorted(where string) ([]Match, error) {
 	query := `
 		SELECT m.id, m.tournament_id, m.round, m.player1_id, m.player2_id, 
 		       m.player1_wins, m.player2_wins, m.date_played, t.date as tournament_date
 		FROM matches m
 		JOIN tournaments t ON m.tournament_id = t.melee_id
-		ORDER BY COALESCE(t.date, '1970-01-01') ASC, m.tournament_id ASC, m.round ASC
+		` + where + `
+		ORDER BY COALESCE(t.date, '1970-01-01') ASC, m.tournament_id ASC, m.round ASC, m.rowid ASC
 	`
 
 	rows, err := s.db.Query(query)
@@ -398,6 +935,250 @@ func (s *Storage) MatchExists(matchID string) (bool, error) {
 	return count > 0, nil
 }
 
+// SaveMatches inserts several matches in a single transaction.
+func (s *Storage) SaveMatches(matches []Match) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO matches (id, tournament_id, round, player1_id, player2_id, player1_wins, player2_wins,
+		date_played, player1_elo_before, player2_elo_before, player1_elo_after, player2_elo_after)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, match := range matches {
+		_, err := stmt.Exec(
+			match.ID, match.TournamentID, match.Round, match.Player1ID, match.Player2ID,
+			match.Player1Wins, match.Player2Wins, match.DatePlayed,
+			match.Player1ELOBefore, match.Player2ELOBefore, match.Player1ELOAfter, match.Player2ELOAfter,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save match %s: %w", match.ID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetTournamentMatches returns the stored matches of a tournament, including
+// the external IDs of both players.
+func (s *Storage) GetTournamentMatches(meleeID int) ([]Match, error) {
+	query := `
+		SELECT m.id, m.tournament_id, m.round, m.player1_id, m.player2_id,
+		       p1.external_id, p2.external_id, m.player1_wins, m.player2_wins
+		FROM matches m
+		JOIN players p1 ON m.player1_id = p1.id
+		JOIN players p2 ON m.player2_id = p2.id
+		WHERE m.tournament_id = ?
+		ORDER BY m.round ASC, m.id ASC
+	`
+
+	rows, err := s.db.Query(query, meleeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []Match
+	for rows.Next() {
+		var m Match
+		err := rows.Scan(&m.ID, &m.TournamentID, &m.Round, &m.Player1ID, &m.Player2ID,
+			&m.Player1ExternalID, &m.Player2ExternalID, &m.Player1Wins, &m.Player2Wins)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+
+	return matches, rows.Err()
+}
+
+// TournamentMatch is a stored match of a tournament with both players' names
+// and ratings.
+type TournamentMatch struct {
+	TournamentID     int
+	TournamentName   string
+	Date             time.Time
+	Round            int
+	Player1          string
+	Player2          string
+	Player1Wins      int
+	Player2Wins      int
+	Player1ELOBefore int
+	Player2ELOBefore int
+	Player1ELOAfter  int
+	Player2ELOAfter  int
+}
+
+// GetTournamentResults returns the matches of a tournament by round. The
+// ratings are zero until the tournament is rated.
+func (s *Storage) GetTournamentResults(meleeID int) ([]TournamentMatch, error) {
+	return s.queryTournamentMatches(`WHERE t.melee_id = ?`, meleeID)
+}
+
+// GetDatedMatches returns the matches of every tournament with a date, in
+// the order they are rated.
+func (s *Storage) GetDatedMatches() ([]TournamentMatch, error) {
+	return s.queryTournamentMatches(`WHERE t.date IS NOT NULL`)
+}
+
+func (s *Storage) queryTournamentMatches(where string, args ...interface{}) ([]TournamentMatch, error) {
+	query := `
+		SELECT t.melee_id, COALESCE(t.name, ''), t.date, m.round,
+		       p1.display_name, p2.display_name, m.player1_wins, m.player2_wins,
+		       COALESCE(m.player1_elo_before, 0), COALESCE(m.player2_elo_before, 0),
+		       COALESCE(m.player1_elo_after, 0), COALESCE(m.player2_elo_after, 0)
+		FROM matches m
+		JOIN public_players p1 ON m.player1_id = p1.id
+		JOIN public_players p2 ON m.player2_id = p2.id
+		JOIN tournaments t ON m.tournament_id = t.melee_id
+		` + where + `
+		ORDER BY COALESCE(t.date, '1970-01-01') ASC, t.melee_id ASC, m.round ASC, m.rowid ASC
+	`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []TournamentMatch
+	for rows.Next() {
+		var m TournamentMatch
+		var datePtr *time.Time
+		err := rows.Scan(&m.TournamentID, &m.TournamentName, &datePtr, &m.Round,
+			&m.Player1, &m.Player2, &m.Player1Wins, &m.Player2Wins,
+			&m.Player1ELOBefore, &m.Player2ELOBefore, &m.Player1ELOAfter, &m.Player2ELOAfter)
+		if err != nil {
+			return nil, err
+		}
+		if datePtr != nil {
+			m.Date = *datePtr
+		}
+		matches = append(matches, m)
+	}
+
+	return matches, rows.Err()
+}
+
+// TournamentPerformance is how a player did at one rated tournament.
+// Performance is the rating at which their results against those opponents
+// would have been expected.
+type TournamentPerformance struct {
+	TournamentID    int
+	TournamentName  string
+	Date            time.Time
+	Player          string
+	Wins            int
+	Losses          int
+	Draws           int
+	OpponentAverage int
+	ELOBefore       int
+	ELOAfter        int
+	Performance     int
+}
+
+// GetTournamentPerformances returns the performance of every player at a
+// rated tournament, in the order they first played.
+func (s *Storage) GetTournamentPerformances(meleeID int) ([]TournamentPerformance, error) {
+	matches, err := s.GetTournamentResults(meleeID)
+	if err != nil {
+		return nil, err
+	}
+	return Performances(matches), nil
+}
+
+// GetPlayerPerformances returns a player's performance at every rated
+// tournament they played, oldest first.
+func (s *Storage) GetPlayerPerformances(displayName string) ([]TournamentPerformance, error) {
+	matches, err := s.queryTournamentMatches(
+		`WHERE t.date IS NOT NULL AND (p1.display_name = ? OR p2.display_name = ?)`, displayName, displayName)
+	if err != nil {
+		return nil, err
+	}
+	var performances []TournamentPerformance
+	for _, p := range Performances(matches) {
+		if p.Player == displayName {
+			performances = append(performances, p)
+		}
+	}
+	return performances, nil
+}
+
+// Performances sums matches, ordered as queryTournamentMatches returns
+// them, into one performance per player per tournament. Sets without
+// ratings are left out, so unrated tournaments have none.
+func Performances(matches []TournamentMatch) []TournamentPerformance {
+	type key struct {
+		tournament int
+		player     string
+	}
+	var performances []TournamentPerformance
+	index := make(map[key]int)
+	opponents := make(map[key][]int)
+	scores := make(map[key]float64)
+
+	for _, m := range matches {
+		if m.Player1ELOAfter == 0 || m.Player2ELOAfter == 0 {
+			continue
+		}
+		sides := []struct {
+			player         string
+			before, after  int
+			opponentBefore int
+			wins, losses   int
+		}{
+			{m.Player1, m.Player1ELOBefore, m.Player1ELOAfter, m.Player2ELOBefore, m.Player1Wins, m.Player2Wins},
+			{m.Player2, m.Player2ELOBefore, m.Player2ELOAfter, m.Player1ELOBefore, m.Player2Wins, m.Player1Wins},
+		}
+		for _, side := range sides {
+			k := key{m.TournamentID, side.player}
+			i, ok := index[k]
+			if !ok {
+				// The first set holds the rating the player entered with
+				i = len(performances)
+				index[k] = i
+				performances = append(performances, TournamentPerformance{
+					TournamentID:   m.TournamentID,
+					TournamentName: m.TournamentName,
+					Date:           m.Date,
+					Player:         side.player,
+					ELOBefore:      side.before,
+				})
+			}
+			p := &performances[i]
+			p.ELOAfter = side.after
+			opponents[k] = append(opponents[k], side.opponentBefore)
+			switch {
+			case side.wins > side.losses:
+				p.Wins++
+				scores[k]++
+			case side.wins < side.losses:
+				p.Losses++
+			default:
+				p.Draws++
+				scores[k] += 0.5
+			}
+		}
+	}
+
+	for k, i := range index {
+		p := &performances[i]
+		sum := 0
+		for _, opponent := range opponents[k] {
+			sum += opponent
+		}
+		p.OpponentAverage = int(math.Round(float64(sum) / float64(len(opponents[k]))))
+		p.Performance = elo.PerformanceRating(opponents[k], scores[k])
+	}
+	return performances
+}
+
 func (s *Storage) SaveMatch(match Match) error {
 	_, err := s.db.Exec(
 		`INSERT INTO matches (id, tournament_id, round, player1_id, player2_id, player1_wins, player2_wins, 
@@ -410,6 +1191,20 @@ func (s *Storage) SaveMatch(match Match) error {
 	return err
 }
 
+// UpdateMatchResult overwrites the round, players and score of a stored match.
+func (s *Storage) UpdateMatchResult(match Match) error {
+	_, err := s.db.Exec(
+		`UPDATE matches SET round = ?, player1_id = ?, player2_id = ?, player1_wins = ?, player2_wins = ? WHERE id = ?`,
+		match.Round, match.Player1ID, match.Player2ID, match.Player1Wins, match.Player2Wins, match.ID,
+	)
+	return err
+}
+
+func (s *Storage) DeleteMatch(matchID string) error {
+	_, err := s.db.Exec("DELETE FROM matches WHERE id = ?", matchID)
+	return err
+}
+
 func (s *Storage) UpdateMatchELO(matchID string, player1ELOBefore, player2ELOBefore, player1ELOAfter, player2ELOAfter int) error {
 	_, err := s.db.Exec(
 		`UPDATE matches SET player1_elo_before = ?, player2_elo_before = ?, player1_elo_after = ?, player2_elo_after = ? WHERE id = ?`,
@@ -418,10 +1213,110 @@ func (s *Storage) UpdateMatchELO(matchID string, player1ELOBefore, player2ELOBef
 	return err
 }
 
+// RatingUpdate is the result of replaying matches in memory: the new totals of
+// the affected players, the ratings to record on each match and each
+// player's totals after every tournament they played.
+type RatingUpdate struct {
+	Players []Player
+	Matches []Match
+	History []RatingHistoryEntry
+
+	// RatedTournaments are flagged as applied to ratings, or every
+	// tournament is when RateAll is set.
+	RatedTournaments []int
+	RateAll          bool
+}
+
+// RatingHistoryEntry is a player's rating and totals after a tournament.
+type RatingHistoryEntry struct {
+	PlayerID      int64
+	TournamentID  int
+	Date          time.Time
+	ELO           int
+	MatchesPlayed int
+	Wins          int
+	Losses        int
+}
+
+// ApplyRatingUpdate writes a rating update in a single transaction, so a
+// failed rebuild never leaves ratings half written.
+func (s *Storage) ApplyRatingUpdate(update RatingUpdate) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	playerStmt, err := tx.Prepare(`UPDATE players
+		SET current_elo = ?, matches_played = ?, wins = ?, losses = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`)
+	if err != nil {
+		return err
+	}
+	defer playerStmt.Close()
+
+	for _, p := range update.Players {
+		if _, err := playerStmt.Exec(p.CurrentELO, p.MatchesPlayed, p.Wins, p.Losses, p.ID); err != nil {
+			return fmt.Errorf("failed to update player %d: %w", p.ID, err)
+		}
+	}
+
+	matchStmt, err := tx.Prepare(`UPDATE matches
+		SET player1_elo_before = ?, player2_elo_before = ?, player1_elo_after = ?, player2_elo_after = ?
+		WHERE id = ?`)
+	if err != nil {
+		return err
+	}
+	defer matchStmt.Close()
+
+	for _, m := range update.Matches {
+		if _, err := matchStmt.Exec(m.Player1ELOBefore, m.Player2ELOBefore, m.Player1ELOAfter, m.Player2ELOAfter, m.ID); err != nil {
+			return fmt.Errorf("failed to update match %s: %w", m.ID, err)
+		}
+	}
+
+	// A full rebuild rewrites the whole history
+	if update.RateAll {
+		if _, err := tx.Exec("DELETE FROM rating_history"); err != nil {
+			return err
+		}
+	}
+	historyStmt, err := tx.Prepare(`INSERT OR REPLACE INTO rating_history
+		(player_id, tournament_id, date, elo, matches_played, wins, losses)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer historyStmt.Close()
+
+	for _, h := range update.History {
+		var date *time.Time
+		if !h.Date.IsZero() {
+			date = &h.Date
+		}
+		if _, err := historyStmt.Exec(h.PlayerID, h.TournamentID, date, h.ELO, h.MatchesPlayed, h.Wins, h.Losses); err != nil {
+			return fmt.Errorf("failed to record rating history of player %d: %w", h.PlayerID, err)
+		}
+	}
+
+	if update.RateAll {
+		if _, err := tx.Exec("UPDATE tournaments SET rated = 1"); err != nil {
+			return err
+		}
+	}
+	for _, id := range update.RatedTournaments {
+		if _, err := tx.Exec("UPDATE tournaments SET rated = 1 WHERE melee_id = ?", id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
 func (s *Storage) GetRankings() ([]Ranking, error) {
 	query := `SELECT 
-		display_name, username, current_elo, matches_played, wins, losses
-	  FROM players 
+		id, display_name, username, current_elo, matches_played, wins, losses
+	  FROM public_players 
 	  WHERE matches_played >= 10
 	  ORDER BY current_elo DESC`
 
@@ -436,7 +1331,7 @@ func (s *Storage) GetRankings() ([]Ranking, error) {
 	for rows.Next() {
 		var r Ranking
 		var username sql.NullString
-		err := rows.Scan(&r.DisplayName, &username, &r.CurrentELO, &r.MatchesPlayed, &r.Wins, &r.Losses)
+		err := rows.Scan(&r.PlayerID, &r.DisplayName, &username, &r.CurrentELO, &r.MatchesPlayed, &r.Wins, &r.Losses)
 		if err != nil {
 			return nil, err
 		}
@@ -458,9 +1353,268 @@ func (s *Storage) GetRankings() ([]Ranking, error) {
 	return rankings, rows.Err()
 }
 
+// HasRatingHistory reports whether any rating history has been recorded.
+func (s *Storage) HasRatingHistory() (bool, error) {
+	var exists bool
+	err := s.db.QueryRow("SELECT EXISTS (SELECT 1 FROM rating_history)").Scan(&exists)
+	return exists, err
+}
+
+// GetRankingsAsOf ranks the players with at least minMatches matches by
+// their rating after the last tournament on or before date. Tournaments
+// without a date count as the oldest, as they do when rating.
+func (s *Storage) GetRankingsAsOf(date time.Time, minMatches int) ([]Ranking, error) {
+	// Tournaments stored with a time of day still count on their day
+	nextDay := time.Date(date.Year(), date.Month(), date.Day()+1, 0, 0, 0, 0, time.UTC)
+	rows, err := s.db.Query(`
+		SELECT p.id, p.display_name, p.username, h.elo, h.matches_played, h.wins, h.losses
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (
+				PARTITION BY player_id
+				ORDER BY COALESCE(date, '1970-01-01') DESC, tournament_id DESC
+			) AS latest
+			FROM rating_history
+			WHERE COALESCE(date, '1970-01-01') < ?
+		) h
+		JOIN public_players p ON h.player_id = p.id
+		WHERE h.latest = 1
+	`, nextDay)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var players []Player
+	for rows.Next() {
+		var p Player
+		var username sql.NullString
+		if err := rows.Scan(&p.ID, &p.DisplayName, &username, &p.CurrentELO, &p.MatchesPlayed, &p.Wins, &p.Losses); err != nil {
+			return nil, err
+		}
+		p.Username = username.String
+		players = append(players, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return RankPlayers(players, minMatches), nil
+}
+
+// RankPlayers ranks the players with at least minMatches matches by rating,
+// the same way GetRankings ranks the stored players.
+func RankPlayers(players []Player, minMatches int) []Ranking {
+	eligible := make([]Player, 0, len(players))
+	for _, p := range players {
+		if p.MatchesPlayed >= minMatches && p.MatchesPlayed > 0 {
+			eligible = append(eligible, p)
+		}
+	}
+	sort.SliceStable(eligible, func(i, j int) bool {
+		if eligible[i].CurrentELO != eligible[j].CurrentELO {
+			return eligible[i].CurrentELO > eligible[j].CurrentELO
+		}
+		return eligible[i].DisplayName < eligible[j].DisplayName
+	})
+
+	rankings := make([]Ranking, len(eligible))
+	for i, p := range eligible {
+		rankings[i] = Ranking{
+			PlayerID:      p.ID,
+			Rank:          i + 1,
+			DisplayName:   p.DisplayName,
+			Username:      p.Username,
+			CurrentELO:    p.CurrentELO,
+			MatchesPlayed: p.MatchesPlayed,
+			Wins:          p.Wins,
+			Losses:        p.Losses,
+			WinRate:       float64(p.Wins) / float64(p.MatchesPlayed) * 100,
+		}
+	}
+	return rankings
+}
+
+// PlayerGroup is a profile field that players can be ranked within.
+type PlayerGroup string
+
+const (
+	GroupRegion PlayerGroup = "region"
+	GroupTeam   PlayerGroup = "team"
+)
+
+func (g PlayerGroup) column() (string, error) {
+	switch g {
+	case GroupRegion, GroupTeam:
+		return string(g), nil
+	}
+	return "", fmt.Errorf("unknown player group %q", g)
+}
+
+// GetGroups returns every region or team that a published profile names,
+// in alphabetical order.
+func (s *Storage) GetGroups(group PlayerGroup) ([]string, error) {
+	column, err := group.column()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`SELECT DISTINCT ` + column + ` FROM public_players WHERE ` + column + ` != '' ORDER BY ` + column)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// GetGroupRankings ranks the players of one region or team among
+// themselves, the same way RankPlayers ranks everyone.
+func (s *Storage) GetGroupRankings(group PlayerGroup, name string, minMatches int) ([]Ranking, error) {
+	column, err := group.column()
+	if err != nil {
+		return nil, err
+	}
+	players, err := s.queryPlayers(`WHERE `+column+` = ?`, name)
+	if err != nil {
+		return nil, err
+	}
+	return RankPlayers(players, minMatches), nil
+}
+
+// ClubRanking is a team ranked by the average rating of its best members.
+// Members counts the team's ranked players.
+type ClubRanking struct {
+	Rank       int
+	Team       string
+	Members    int
+	AverageELO int
+}
+
+// GetClubRankings ranks the teams with at least topN ranked players by the
+// average rating of their topN best. Players are ranked as RankPlayers
+// ranks them.
+func (s *Storage) GetClubRankings(topN, minMatches int) ([]ClubRanking, error) {
+	rows, err := s.db.Query(`
+		WITH members AS (
+			SELECT team, current_elo,
+			       ROW_NUMBER() OVER (PARTITION BY team ORDER BY current_elo DESC, display_name ASC) AS position,
+			       COUNT(*) OVER (PARTITION BY team) AS members
+			FROM public_players
+			WHERE team != '' AND matches_played >= ? AND matches_played > 0
+		)
+		SELECT team, MAX(members), AVG(current_elo) AS average
+		FROM members
+		WHERE position <= ?
+		GROUP BY team
+		HAVING COUNT(*) >= ?
+		ORDER BY average DESC, team ASC`, minMatches, topN, topN)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clubs []ClubRanking
+	for rows.Next() {
+		var c ClubRanking
+		var average float64
+		if err := rows.Scan(&c.Team, &c.Members, &average); err != nil {
+			return nil, err
+		}
+		c.Rank = len(clubs) + 1
+		c.AverageELO = int(math.Round(average))
+		clubs = append(clubs, c)
+	}
+	return clubs, rows.Err()
+}
+
+// SnapshotEntry is a player's standing in a ranking snapshot.
+type SnapshotEntry struct {
+	Date        time.Time
+	PlayerID    int64
+	DisplayName string
+	Rank        int
+	ELO         int
+}
+
+// SaveRankingSnapshot records the rankings as they stood after the
+// tournaments of the given date, replacing any earlier snapshot of that date.
+func (s *Storage) SaveRankingSnapshot(date time.Time, rankings []Ranking) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM ranking_snapshots WHERE snapshot_date = ?", date); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare("INSERT INTO ranking_snapshots (snapshot_date, player_id, rank, elo) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, r := range rankings {
+		if _, err := stmt.Exec(date, r.PlayerID, r.Rank, r.CurrentELO); err != nil {
+			return fmt.Errorf("failed to save snapshot of player %d: %w", r.PlayerID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetRankingSnapshotBefore returns the latest snapshot taken before date, by
+// rank. It returns nil if there is none.
+func (s *Storage) GetRankingSnapshotBefore(date time.Time) ([]SnapshotEntry, error) {
+	return s.querySnapshots(`
+		WHERE rs.snapshot_date = (SELECT MAX(snapshot_date) FROM ranking_snapshots WHERE snapshot_date < ?)
+		ORDER BY rs.rank ASC`, date)
+}
+
+// GetRankingSnapshots returns every snapshot entry, oldest snapshot first and
+// by rank within a snapshot.
+func (s *Storage) GetRankingSnapshots() ([]SnapshotEntry, error) {
+	return s.querySnapshots(`ORDER BY rs.snapshot_date ASC, rs.rank ASC`)
+}
+
+// GetRankHistory returns every snapshot a player was ranked in, oldest first.
+func (s *Storage) GetRankHistory(playerID int64) ([]SnapshotEntry, error) {
+	return s.querySnapshots(`
+		WHERE rs.player_id = ?
+		ORDER BY rs.snapshot_date ASC`, playerID)
+}
+
+func (s *Storage) querySnapshots(where string, args ...interface{}) ([]SnapshotEntry, error) {
+	rows, err := s.db.Query(`
+		SELECT rs.snapshot_date, rs.player_id, p.display_name, rs.rank, rs.elo
+		FROM ranking_snapshots rs
+		JOIN public_players p ON rs.player_id = p.id
+		`+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []SnapshotEntry
+	for rows.Next() {
+		var e SnapshotEntry
+		if err := rows.Scan(&e.Date, &e.PlayerID, &e.DisplayName, &e.Rank, &e.ELO); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
 type PlayerMatch struct {
 	DatePlayed      time.Time
 	TournamentID    int
+	TournamentName  string
 	Round           int
 	OpponentName    string
 	PlayerWins      int
@@ -474,6 +1628,8 @@ func (s *Storage) GetPlayerMatchHistory(displayName string) ([]PlayerMatch, erro
 	query := `
 		SELECT 
 			t.date as tournament_date,
+			t.melee_id,
+			COALESCE(t.name, '') as tournament_name,
 			m.round,
 			CASE 
 				WHEN p1.display_name = ? THEN p2.display_name
@@ -496,8 +1652,8 @@ func (s *Storage) GetPlayerMatchHistory(displayName string) ([]PlayerMatch, erro
 				ELSE m.player2_elo_after
 			END as player_elo_after
 		FROM matches m
-		JOIN players p1 ON m.player1_id = p1.id
-		JOIN players p2 ON m.player2_id = p2.id
+		JOIN public_players p1 ON m.player1_id = p1.id
+		JOIN public_players p2 ON m.player2_id = p2.id
 		JOIN tournaments t ON m.tournament_id = t.melee_id
 		WHERE (p1.display_name = ? OR p2.display_name = ?)
 		  AND t.date IS NOT NULL
@@ -515,6 +1671,8 @@ func (s *Storage) GetPlayerMatchHistory(displayName string) ([]PlayerMatch, erro
 		var m PlayerMatch
 		err := rows.Scan(
 			&m.DatePlayed,
+			&m.TournamentID,
+			&m.TournamentName,
 			&m.Round,
 			&m.OpponentName,
 			&m.PlayerWins,
@@ -541,6 +1699,29 @@ func (s *Storage) GetPlayerMatchHistory(displayName string) ([]PlayerMatch, erro
 	return matches, rows.Err()
 }
 
+// Reverse returns the same matchup from Player2's side.
+func (m Matchup) Reverse() Matchup {
+	r := Matchup{
+		Player1:     m.Player2,
+		Player2:     m.Player1,
+		Player1Sets: m.Player2Sets,
+		Player2Sets: m.Player1Sets,
+		SetsPlayed:  m.SetsPlayed,
+		Player1Wins: m.Player2Wins,
+		Player2Wins: m.Player1Wins,
+		GamesPlayed: m.GamesPlayed,
+	}
+	if r.SetsPlayed > 0 {
+		r.Player1SetWinRate = float64(r.Player1Sets) / float64(r.SetsPlayed) * 100
+	}
+	if r.GamesPlayed > 0 {
+		r.Player1WinRate = float64(r.Player1Wins) / float64(r.GamesPlayed) * 100
+	}
+	return r
+}
+
+// GetMatchups returns one matchup per pair of players that has played, with
+// the alphabetically first player as Player1.
 func (s *Storage) GetMatchups() ([]Matchup, error) {
 	query := `
 		WITH normalized_matchups AS (
@@ -550,14 +1731,17 @@ func (s *Storage) GetMatchups() ([]Matchup, error) {
 				CASE WHEN p1.display_name < p2.display_name THEN m.player1_wins ELSE m.player2_wins END as wins_a,
 				CASE WHEN p1.display_name < p2.display_name THEN m.player2_wins ELSE m.player1_wins END as wins_b
 			FROM matches m
-			JOIN players p1 ON m.player1_id = p1.id
-			JOIN players p2 ON m.player2_id = p2.id
+			JOIN public_players p1 ON m.player1_id = p1.id
+			JOIN public_players p2 ON m.player2_id = p2.id
 			JOIN tournaments t ON m.tournament_id = t.melee_id
 			WHERE t.date IS NOT NULL
 		)
 		SELECT 
 			player_a as player1,
 			player_b as player2,
+			SUM(CASE WHEN wins_a > wins_b THEN 1 ELSE 0 END) as player1_sets,
+			SUM(CASE WHEN wins_b > wins_a THEN 1 ELSE 0 END) as player2_sets,
+			COUNT(*) as sets_played,
 			SUM(wins_a) as player1_wins,
 			SUM(wins_b) as player2_wins,
 			SUM(wins_a + wins_b) as games_played
@@ -575,11 +1759,15 @@ func (s *Storage) GetMatchups() ([]Matchup, error) {
 	var matchups []Matchup
 	for rows.Next() {
 		var m Matchup
-		err := rows.Scan(&m.Player1, &m.Player2, &m.Player1Wins, &m.Player2Wins, &m.GamesPlayed)
+		err := rows.Scan(&m.Player1, &m.Player2, &m.Player1Sets, &m.Player2Sets, &m.SetsPlayed,
+			&m.Player1Wins, &m.Player2Wins, &m.GamesPlayed)
 		if err != nil {
 			return nil, err
 		}
 
+		if m.SetsPlayed > 0 {
+			m.Player1SetWinRate = float64(m.Player1Sets) / float64(m.SetsPlayed) * 100
+		}
 		if m.GamesPlayed > 0 {
 			m.Player1WinRate = float64(m.Player1Wins) / float64(m.GamesPlayed) * 100
 		}
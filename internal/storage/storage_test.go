@@ -2,7 +2,9 @@ package storage
 
 import (
 	"database/sql"
+	"fmt"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -364,4 +366,693 @@ func TestMatchupsCombinedBidirectional(t *testing.T) {
 	if aliceCharlie.GamesPlayed != 2 {
 		t.Errorf("expected 2 games between TestAlice and TestCharlie, got %d", aliceCharlie.GamesPlayed)
 	}
+
+	// One set each, although Bob won more games
+	if aliceBob.Player1Sets != 1 || aliceBob.Player2Sets != 1 || aliceBob.SetsPlayed != 2 || aliceBob.Player1SetWinRate != 50.0 {
+		t.Errorf("expected sets 1-1 between TestAlice and TestBob, got %d-%d of %d", aliceBob.Player1Sets, aliceBob.Player2Sets, aliceBob.SetsPlayed)
+	}
+
+	bobAlice := aliceBob.Reverse()
+	if bobAlice.Player1 != "TestBob" || bobAlice.Player1Wins != 3 || bobAlice.Player1WinRate != 60.0 || bobAlice.Player2Sets != 1 {
+		t.Errorf("unexpected reversed matchup: %+v", bobAlice)
+	}
+}
+
+func TestTournamentSource(t *testing.T) {
+	store := createTestDB(t)
+	defer store.Close()
+
+	tournamentDate := time.Date(2024, 10, 17, 0, 0, 0, 0, time.UTC)
+	store.GetOrCreateTournament(170676, tournamentDate)
+
+	if err := store.UpdateTournamentSource(170676, "abc123", "Matches-tournament-170676.json"); err != nil {
+		t.Fatalf("failed to update source: %v", err)
+	}
+
+	byHash, err := store.GetTournamentByContentHash("abc123")
+	if err != nil {
+		t.Fatalf("failed to get tournament by hash: %v", err)
+	}
+	if byHash == nil || byHash.MeleeID != 170676 {
+		t.Fatalf("expected tournament 170676, got %+v", byHash)
+	}
+	if byHash.SourceFilename != "Matches-tournament-170676.json" {
+		t.Errorf("expected source filename, got %s", byHash.SourceFilename)
+	}
+
+	missing, err := store.GetTournamentByContentHash("other")
+	if err != nil {
+		t.Fatalf("failed to look up unknown hash: %v", err)
+	}
+	if missing != nil {
+		t.Errorf("expected no tournament for unknown hash, got %+v", missing)
+	}
+}
+
+func TestTournamentMetadata(t *testing.T) {
+	store := createTestDB(t)
+	defer store.Close()
+
+	store.GetOrCreateTournament(170676, time.Date(2024, 10, 17, 0, 0, 0, 0, time.UTC))
+
+	if err := store.UpdateTournamentMetadata(170676, "Weekly #12", "local", "Berlin", "Club"); err != nil {
+		t.Fatalf("failed to update metadata: %v", err)
+	}
+
+	tournament, err := store.GetTournamentByMeleeID(170676)
+	if err != nil {
+		t.Fatalf("failed to get tournament: %v", err)
+	}
+	if tournament.Name != "Weekly #12" || tournament.Tier != "local" || tournament.Location != "Berlin" || tournament.Organizer != "Club" {
+		t.Errorf("unexpected metadata: %+v", tournament)
+	}
+}
+
+func TestTournamentMatchesUpdateAndDelete(t *testing.T) {
+	store := createTestDB(t)
+	defer store.Close()
+
+	alice, _ := store.GetOrCreatePlayer(1, "Alice", "alice")
+	bob, _ := store.GetOrCreatePlayer(2, "Bob", "bob")
+	store.GetOrCreateTournament(1, time.Date(2024, 10, 17, 0, 0, 0, 0, time.UTC))
+
+	store.SaveMatch(Match{
+		ID: "match-1", TournamentID: 1, Round: 1,
+		Player1ID: alice.ID, Player2ID: bob.ID,
+		Player1Wins: 2, Player2Wins: 1,
+	})
+	store.SaveMatch(Match{
+		ID: "match-2", TournamentID: 1, Round: 2,
+		Player1ID: alice.ID, Player2ID: bob.ID,
+		Player1Wins: 0, Player2Wins: 2,
+	})
+
+	matches, err := store.GetTournamentMatches(1)
+	if err != nil {
+		t.Fatalf("failed to get tournament matches: %v", err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got %d", len(matches))
+	}
+	if matches[0].Player1ExternalID != 1 || matches[0].Player2ExternalID != 2 {
+		t.Errorf("expected external IDs 1 and 2, got %d and %d", matches[0].Player1ExternalID, matches[0].Player2ExternalID)
+	}
+
+	updated := matches[0]
+	updated.Player1Wins = 1
+	updated.Player2Wins = 2
+	if err := store.UpdateMatchResult(updated); err != nil {
+		t.Fatalf("failed to update match: %v", err)
+	}
+	if err := store.DeleteMatch("match-2"); err != nil {
+		t.Fatalf("failed to delete match: %v", err)
+	}
+
+	matches, _ = store.GetTournamentMatches(1)
+	if len(matches) != 1 {
+		t.Fatalf("expected 1 match after delete, got %d", len(matches))
+	}
+	if matches[0].Player1Wins != 1 || matches[0].Player2Wins != 2 {
+		t.Errorf("expected updated score 1-2, got %d-%d", matches[0].Player1Wins, matches[0].Player2Wins)
+	}
+}
+
+func TestCopyToMemory(t *testing.T) {
+	store := createTestDB(t)
+	defer store.Close()
+
+	store.GetOrCreatePlayer(1, "Alice", "alice")
+
+	memStore, err := store.CopyToMemory()
+	if err != nil {
+		t.Fatalf("failed to copy database: %v", err)
+	}
+	defer memStore.Close()
+
+	players, err := memStore.GetAllPlayers()
+	if err != nil {
+		t.Fatalf("failed to get players from copy: %v", err)
+	}
+	if len(players) != 1 || players[0].DisplayName != "Alice" {
+		t.Fatalf("expected Alice in copy, got %+v", players)
+	}
+
+	memStore.GetOrCreatePlayer(2, "Bob", "bob")
+	memStore.UpdatePlayerELO(players[0].ID, 1600, true)
+
+	original, err := store.GetAllPlayers()
+	if err != nil {
+		t.Fatalf("failed to get players from original: %v", err)
+	}
+	if len(original) != 1 {
+		t.Errorf("expected original to keep 1 player, got %d", len(original))
+	}
+	if original[0].CurrentELO != 1500 {
+		t.Errorf("expected original ELO 1500, got %d", original[0].CurrentELO)
+	}
+}
+
+func TestRatedTournaments(t *testing.T) {
+	store := createTestDB(t)
+	defer store.Close()
+
+	store.GetOrCreateTournament(1, time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC))
+	store.GetOrCreateTournament(2, time.Date(2024, 8, 8, 0, 0, 0, 0, time.UTC))
+	store.GetOrCreateTournament(3, time.Date(2024, 8, 15, 0, 0, 0, 0, time.UTC))
+
+	last, err := store.GetLastRatedTournament()
+	if err != nil {
+		t.Fatalf("failed to get last rated tournament: %v", err)
+	}
+	if last != nil {
+		t.Errorf("expected no rated tournament, got %+v", last)
+	}
+
+	if err := store.ApplyRatingUpdate(RatingUpdate{RatedTournaments: []int{1, 2}}); err != nil {
+		t.Fatalf("failed to mark tournaments rated: %v", err)
+	}
+
+	last, _ = store.GetLastRatedTournament()
+	if last == nil || last.MeleeID != 2 {
+		t.Errorf("expected tournament 2 to be last rated, got %+v", last)
+	}
+
+	unrated, err := store.GetUnratedTournaments()
+	if err != nil {
+		t.Fatalf("failed to get unrated tournaments: %v", err)
+	}
+	if len(unrated) != 1 || unrated[0].MeleeID != 3 {
+		t.Errorf("expected only tournament 3 unrated, got %+v", unrated)
+	}
+
+	if err := store.ApplyRatingUpdate(RatingUpdate{RateAll: true}); err != nil {
+		t.Fatalf("failed to mark all tournaments rated: %v", err)
+	}
+	unrated, _ = store.GetUnratedTournaments()
+	if len(unrated) != 0 {
+		t.Errorf("expected no unrated tournaments, got %d", len(unrated))
+	}
+
+	// Restating a date changes nothing; moving a rated tournament unrates all
+	store.UpdateTournamentDate(2, time.Date(2024, 8, 8, 0, 0, 0, 0, time.UTC))
+	if unrated, _ = store.GetUnratedTournaments(); len(unrated) != 0 {
+		t.Errorf("expected an unchanged date to keep tournaments rated, got %+v", unrated)
+	}
+	if err := store.UpdateTournamentDate(2, time.Date(2024, 8, 20, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("failed to update date: %v", err)
+	}
+	if last, _ = store.GetLastRatedTournament(); last != nil {
+		t.Errorf("expected no rated tournament after moving one, got %+v", last)
+	}
+}
+
+func TestApplyRatingUpdate(t *testing.T) {
+	store := createTestDB(t)
+	defer store.Close()
+
+	alice, _ := store.GetOrCreatePlayer(1, "Alice", "alice")
+	bob, _ := store.GetOrCreatePlayer(2, "Bob", "bob")
+	store.GetOrCreateTournament(1, time.Date(2024, 10, 17, 0, 0, 0, 0, time.UTC))
+
+	err := store.SaveMatches([]Match{
+		{ID: "match-1", TournamentID: 1, Round: 1, Player1ID: alice.ID, Player2ID: bob.ID, Player1Wins: 2, Player2Wins: 0},
+	})
+	if err != nil {
+		t.Fatalf("failed to save matches: %v", err)
+	}
+
+	alice.CurrentELO, alice.MatchesPlayed, alice.Wins = 1520, 1, 1
+	bob.CurrentELO, bob.MatchesPlayed, bob.Losses = 1480, 1, 1
+	err = store.ApplyRatingUpdate(RatingUpdate{
+		Players: []Player{*alice, *bob},
+		Matches: []Match{{
+			ID:               "match-1",
+			Player1ELOBefore: 1500, Player2ELOBefore: 1500,
+			Player1ELOAfter: 1520, Player2ELOAfter: 1480,
+		}},
+		RatedTournaments: []int{1},
+	})
+	if err != nil {
+		t.Fatalf("failed to apply rating update: %v", err)
+	}
+
+	updated, _ := store.GetPlayerByID(alice.ID)
+	if updated.CurrentELO != 1520 || updated.MatchesPlayed != 1 || updated.Wins != 1 {
+		t.Errorf("unexpected player after update: %+v", updated)
+	}
+
+	history, _ := store.GetPlayerMatchHistory("Bob")
+	if len(history) != 1 || history[0].PlayerELOAfter != 1480 {
+		t.Errorf("expected match ELO to be recorded, got %+v", history)
+	}
+
+	unrated, _ := store.GetUnratedTournaments()
+	if len(unrated) != 0 {
+		t.Errorf("expected tournament to be marked rated, got %+v", unrated)
+	}
+}
+
+func TestRatingHistory(t *testing.T) {
+	store := createTestDB(t)
+	defer store.Close()
+
+	alice, _ := store.GetOrCreatePlayer(1, "Alice", "alice")
+	bob, _ := store.GetOrCreatePlayer(2, "Bob", "bob")
+	week1 := time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+	week2 := time.Date(2024, 8, 8, 0, 0, 0, 0, time.UTC)
+
+	if has, _ := store.HasRatingHistory(); has {
+		t.Fatal("expected no rating history in a new database")
+	}
+	err := store.ApplyRatingUpdate(RatingUpdate{
+		History: []RatingHistoryEntry{
+			{PlayerID: alice.ID, TournamentID: 1, Date: week1, ELO: 1516, MatchesPlayed: 1, Wins: 1},
+			{PlayerID: bob.ID, TournamentID: 1, Date: week1, ELO: 1484, MatchesPlayed: 1, Losses: 1},
+			{PlayerID: bob.ID, TournamentID: 2, Date: week2, ELO: 1530, MatchesPlayed: 3, Wins: 2, Losses: 1},
+		},
+		RateAll: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to apply rating update: %v", err)
+	}
+	if has, _ := store.HasRatingHistory(); !has {
+		t.Fatal("expected rating history to be recorded")
+	}
+
+	tests := []struct {
+		asOf time.Time
+		want []string
+	}{
+		{week1.AddDate(0, 0, -1), nil},
+		{week1, []string{"Alice 1516", "Bob 1484"}},
+		{week2.AddDate(0, 0, -1), []string{"Alice 1516", "Bob 1484"}},
+		{week2, []string{"Bob 1530", "Alice 1516"}},
+	}
+	for _, tt := range tests {
+		rankings, err := store.GetRankingsAsOf(tt.asOf, 1)
+		if err != nil {
+			t.Fatalf("GetRankingsAsOf failed: %v", err)
+		}
+		var got []string
+		for _, r := range rankings {
+			got = append(got, fmt.Sprintf("%s %d", r.DisplayName, r.CurrentELO))
+		}
+		if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+			t.Errorf("as of %s: expected %v, got %v", tt.asOf.Format("2006-01-02"), tt.want, got)
+		}
+	}
+
+	// A full rebuild replaces the history
+	err = store.ApplyRatingUpdate(RatingUpdate{
+		History: []RatingHistoryEntry{{PlayerID: alice.ID, TournamentID: 2, Date: week2, ELO: 1510, MatchesPlayed: 1}},
+		RateAll: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to apply rating update: %v", err)
+	}
+	rankings, _ := store.GetRankingsAsOf(week2, 1)
+	if len(rankings) != 1 || rankings[0].DisplayName != "Alice" || rankings[0].PlayerID != alice.ID {
+		t.Errorf("expected the old history to be dropped, got %+v", rankings)
+	}
+}
+
+func TestRankingsAsOfCountsTimeOfDay(t *testing.T) {
+	store := createTestDB(t)
+	defer store.Close()
+
+	alice, _ := store.GetOrCreatePlayer(1, "Alice", "alice")
+	// A date scraped from melee.gg carries the start time
+	evening := time.Date(2024, 8, 1, 19, 0, 0, 0, time.UTC)
+	err := store.ApplyRatingUpdate(RatingUpdate{
+		History: []RatingHistoryEntry{{PlayerID: alice.ID, TournamentID: 1, Date: evening, ELO: 1516, MatchesPlayed: 1, Wins: 1}},
+		RateAll: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to apply rating update: %v", err)
+	}
+
+	day := time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+	rankings, err := store.GetRankingsAsOf(day, 1)
+	if err != nil {
+		t.Fatalf("GetRankingsAsOf failed: %v", err)
+	}
+	if len(rankings) != 1 || rankings[0].PlayerID != alice.ID {
+		t.Errorf("expected the tournament to count on its day, got %+v", rankings)
+	}
+	rankings, _ = store.GetRankingsAsOf(day.AddDate(0, 0, -1), 1)
+	if len(rankings) != 0 {
+		t.Errorf("expected no rankings the day before, got %+v", rankings)
+	}
+}
+
+func TestRankingSnapshots(t *testing.T) {
+	store := createTestDB(t)
+	defer store.Close()
+
+	alice, _ := store.GetOrCreatePlayer(1, "Alice", "alice")
+	bob, _ := store.GetOrCreatePlayer(2, "Bob", "bob")
+	week1 := time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+	week2 := time.Date(2024, 8, 8, 0, 0, 0, 0, time.UTC)
+
+	save := func(date time.Time, rankings ...Ranking) {
+		t.Helper()
+		if err := store.SaveRankingSnapshot(date, rankings); err != nil {
+			t.Fatalf("SaveRankingSnapshot failed: %v", err)
+		}
+	}
+	save(week1, Ranking{PlayerID: alice.ID, Rank: 1, CurrentELO: 1516})
+	save(week2, Ranking{PlayerID: bob.ID, Rank: 1, CurrentELO: 1530}, Ranking{PlayerID: alice.ID, Rank: 2, CurrentELO: 1510})
+	// Saving a date again replaces its snapshot
+	save(week2, Ranking{PlayerID: bob.ID, Rank: 1, CurrentELO: 1532}, Ranking{PlayerID: alice.ID, Rank: 2, CurrentELO: 1508})
+
+	previous, err := store.GetRankingSnapshotBefore(week2)
+	if err != nil {
+		t.Fatalf("GetRankingSnapshotBefore failed: %v", err)
+	}
+	if len(previous) != 1 || previous[0].DisplayName != "Alice" || previous[0].Rank != 1 || !previous[0].Date.Equal(week1) {
+		t.Errorf("unexpected previous snapshot: %+v", previous)
+	}
+	if none, _ := store.GetRankingSnapshotBefore(week1); len(none) != 0 {
+		t.Errorf("expected no snapshot before the first, got %+v", none)
+	}
+
+	history, err := store.GetRankHistory(alice.ID)
+	if err != nil {
+		t.Fatalf("GetRankHistory failed: %v", err)
+	}
+	if len(history) != 2 || history[0].Rank != 1 || history[1].Rank != 2 || history[1].ELO != 1508 {
+		t.Errorf("unexpected rank history: %+v", history)
+	}
+
+	all, _ := store.GetRankingSnapshots()
+	if len(all) != 3 || all[1].DisplayName != "Bob" {
+		t.Errorf("unexpected snapshots: %+v", all)
+	}
+}
+
+func TestRankPlayers(t *testing.T) {
+	players := []Player{
+		{DisplayName: "Bob", CurrentELO: 1600, MatchesPlayed: 12, Wins: 9, Losses: 3},
+		{DisplayName: "Alice", CurrentELO: 1600, MatchesPlayed: 10, Wins: 6, Losses: 4},
+		{DisplayName: "Carol", CurrentELO: 1700, MatchesPlayed: 4, Wins: 4},
+		{DisplayName: "Dave", CurrentELO: 1400, MatchesPlayed: 20, Wins: 5, Losses: 15},
+	}
+
+	rankings := RankPlayers(players, 10)
+	if len(rankings) != 3 {
+		t.Fatalf("expected 3 eligible players, got %d", len(rankings))
+	}
+	want := []string{"Alice", "Bob", "Dave"}
+	for i, name := range want {
+		if rankings[i].DisplayName != name || rankings[i].Rank != i+1 {
+			t.Errorf("expected %s at rank %d, got %+v", name, i+1, rankings[i])
+		}
+	}
+	if rankings[0].WinRate != 60 {
+		t.Errorf("expected win rate 60, got %f", rankings[0].WinRate)
+	}
+}
+
+func TestGetAllTournaments(t *testing.T) {
+	store := createTestDB(t)
+	defer store.Close()
+
+	store.GetOrCreateTournament(2, time.Date(2024, 10, 17, 0, 0, 0, 0, time.UTC))
+	store.GetOrCreateTournament(1, time.Date(2024, 10, 24, 0, 0, 0, 0, time.UTC))
+	store.UpdateTournamentMetadata(1, "Regional", "regional", "", "")
+
+	tournaments, err := store.GetAllTournaments()
+	if err != nil {
+		t.Fatalf("failed to get tournaments: %v", err)
+	}
+	if len(tournaments) != 2 || tournaments[0].MeleeID != 2 || tournaments[1].MeleeID != 1 {
+		t.Fatalf("expected tournaments in date order, got %+v", tournaments)
+	}
+	if tournaments[1].Tier != "regional" {
+		t.Errorf("expected tier regional, got %q", tournaments[1].Tier)
+	}
+}
+
+func TestGetTournamentResults(t *testing.T) {
+	store := createTestDB(t)
+	defer store.Close()
+
+	alice, _ := store.GetOrCreatePlayer(1, "Alice", "alice")
+	bob, _ := store.GetOrCreatePlayer(2, "Bob", "bob")
+	store.GetOrCreateTournament(7, time.Date(2024, 10, 17, 0, 0, 0, 0, time.UTC))
+	store.UpdateTournamentMetadata(7, "Weekly #7", "", "", "")
+
+	store.SaveMatch(Match{
+		ID: "m2", TournamentID: 7, Round: 2,
+		Player1ID: bob.ID, Player2ID: alice.ID, Player1Wins: 2, Player2Wins: 1,
+		Player1ELOBefore: 1480, Player2ELOBefore: 1520, Player1ELOAfter: 1500, Player2ELOAfter: 1500,
+	})
+	store.SaveMatch(Match{
+		ID: "m1", TournamentID: 7, Round: 1,
+		Player1ID: alice.ID, Player2ID: bob.ID, Player1Wins: 2, Player2Wins: 0,
+		Player1ELOBefore: 1500, Player2ELOBefore: 1500, Player1ELOAfter: 1520, Player2ELOAfter: 1480,
+	})
+
+	results, err := store.GetTournamentResults(7)
+	if err != nil {
+		t.Fatalf("failed to get results: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 matches, got %d", len(results))
+	}
+	first := results[0]
+	if first.Round != 1 || first.Player1 != "Alice" || first.Player2 != "Bob" || first.Player1ELOAfter != 1520 {
+		t.Errorf("unexpected first match: %+v", first)
+	}
+	if results[1].Player1 != "Bob" || results[1].Player2ELOBefore != 1520 {
+		t.Errorf("unexpected second match: %+v", results[1])
+	}
+
+	history, err := store.GetPlayerMatchHistory("Alice")
+	if err != nil {
+		t.Fatalf("failed to get match history: %v", err)
+	}
+	if len(history) != 2 || history[0].TournamentID != 7 || history[0].TournamentName != "Weekly #7" {
+		t.Errorf("expected history to name the tournament, got %+v", history)
+	}
+
+	// Matches of tournaments without a date are left out
+	store.GetOrCreateTournament(8, time.Time{})
+	store.SaveMatch(Match{ID: "m3", TournamentID: 8, Round: 1, Player1ID: alice.ID, Player2ID: bob.ID, Player1Wins: 2})
+	dated, err := store.GetDatedMatches()
+	if err != nil {
+		t.Fatalf("failed to get dated matches: %v", err)
+	}
+	if len(dated) != 2 || dated[0].TournamentID != 7 || dated[0].TournamentName != "Weekly #7" || dated[0].Date.IsZero() {
+		t.Errorf("expected the two dated matches, got %+v", dated)
+	}
+}
+
+func TestTournamentPerformances(t *testing.T) {
+	store := createTestDB(t)
+	defer store.Close()
+
+	alice, _ := store.GetOrCreatePlayer(1, "Alice", "alice")
+	bob, _ := store.GetOrCreatePlayer(2, "Bob", "bob")
+	carol, _ := store.GetOrCreatePlayer(3, "Carol", "carol")
+	store.GetOrCreateTournament(7, time.Date(2024, 10, 17, 0, 0, 0, 0, time.UTC))
+	store.GetOrCreateTournament(8, time.Date(2024, 10, 24, 0, 0, 0, 0, time.UTC))
+
+	store.SaveMatch(Match{
+		ID: "m1", TournamentID: 7, Round: 1,
+		Player1ID: alice.ID, Player2ID: bob.ID, Player1Wins: 2, Player2Wins: 0,
+		Player1ELOBefore: 1500, Player2ELOBefore: 1600, Player1ELOAfter: 1520, Player2ELOAfter: 1580,
+	})
+	store.SaveMatch(Match{
+		ID: "m2", TournamentID: 7, Round: 2,
+		Player1ID: carol.ID, Player2ID: alice.ID, Player1Wins: 1, Player2Wins: 1,
+		Player1ELOBefore: 1700, Player2ELOBefore: 1520, Player1ELOAfter: 1690, Player2ELOAfter: 1530,
+	})
+	// Tournament 8 is not rated yet
+	store.SaveMatch(Match{ID: "m3", TournamentID: 8, Round: 1, Player1ID: alice.ID, Player2ID: carol.ID, Player1Wins: 2})
+
+	performances, err := store.GetTournamentPerformances(7)
+	if err != nil {
+		t.Fatalf("failed to get performances: %v", err)
+	}
+	if len(performances) != 3 {
+		t.Fatalf("expected 3 performances, got %+v", performances)
+	}
+	a := performances[0]
+	// Alice scored 1.5 of 2 against 1600 and 1700
+	if a.Player != "Alice" || a.Wins != 1 || a.Draws != 1 || a.OpponentAverage != 1650 || a.Performance != 1844 {
+		t.Errorf("unexpected performance for Alice: %+v", a)
+	}
+	if a.ELOBefore != 1500 || a.ELOAfter != 1530 {
+		t.Errorf("expected Alice's ratings going in and out, got %d and %d", a.ELOBefore, a.ELOAfter)
+	}
+	if b := performances[1]; b.Player != "Bob" || b.Losses != 1 || b.Performance != 700 {
+		t.Errorf("unexpected performance for Bob: %+v", b)
+	}
+
+	history, err := store.GetPlayerPerformances("Alice")
+	if err != nil {
+		t.Fatalf("failed to get player performances: %v", err)
+	}
+	if len(history) != 1 || history[0].TournamentID != 7 || history[0].Performance != 1844 {
+		t.Errorf("expected only the rated tournament, got %+v", history)
+	}
+
+	// A lopsided field: the average is the arithmetic mean, not the rating an
+	// even score performs at
+	dave, _ := store.GetOrCreatePlayer(4, "Dave", "dave")
+	store.GetOrCreateTournament(9, time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC))
+	for i, opponent := range []struct {
+		player *Player
+		elo    int
+	}{{bob, 1000}, {carol, 1000}, {alice, 2000}} {
+		store.SaveMatch(Match{
+			ID: fmt.Sprintf("m%d", 4+i), TournamentID: 9, Round: i + 1,
+			Player1ID: dave.ID, Player2ID: opponent.player.ID, Player1Wins: 2,
+			Player1ELOBefore: 1500, Player2ELOBefore: opponent.elo, Player1ELOAfter: 1500, Player2ELOAfter: opponent.elo,
+		})
+	}
+	performances, err = store.GetTournamentPerformances(9)
+	if err != nil {
+		t.Fatalf("failed to get performances: %v", err)
+	}
+	if d := performances[0]; d.Player != "Dave" || d.OpponentAverage != 1333 {
+		t.Errorf("expected Dave's opponents to average 1333, got %+v", d)
+	}
+}
+
+func TestPlayerProfiles(t *testing.T) {
+	store := createTestDB(t)
+	defer store.Close()
+
+	alice, _ := store.GetOrCreatePlayer(1, "Alice", "alice")
+	bob, _ := store.GetOrCreatePlayer(2, "Bob", "bob")
+	store.GetOrCreateTournament(7, time.Date(2024, 10, 17, 0, 0, 0, 0, time.UTC))
+	store.SaveMatch(Match{
+		ID: "m1", TournamentID: 7, Round: 1,
+		Player1ID: alice.ID, Player2ID: bob.ID, Player1Wins: 2, Player2Wins: 0,
+		Player1ELOBefore: 1500, Player2ELOBefore: 1500, Player1ELOAfter: 1516, Player2ELOAfter: 1484,
+	})
+
+	if profile, err := store.GetPlayerProfile(alice.ID); err != nil || profile != nil {
+		t.Fatalf("expected no profile yet, got %+v, %v", profile, err)
+	}
+	err := store.SavePlayerProfile(PlayerProfile{
+		PlayerID: alice.ID, Region: "Paris", Team: "Les Bleus", Main: "Fox",
+		Links: []string{"https://twitter.com/alice", "https://twitch.tv/alice"},
+	})
+	if err != nil {
+		t.Fatalf("failed to save profile: %v", err)
+	}
+	store.SavePlayerProfile(PlayerProfile{PlayerID: bob.ID, Region: "Lyon", OptOut: true})
+
+	profiles, err := store.GetPlayerProfiles()
+	if err != nil {
+		t.Fatalf("failed to get profiles: %v", err)
+	}
+	if len(profiles) != 1 || profiles[0].DisplayName != "Alice" || profiles[0].Main != "Fox" || len(profiles[0].Links) != 2 {
+		t.Errorf("expected only Alice's profile to be published, got %+v", profiles)
+	}
+
+	// Bob's name is gone from everything that is published
+	anonymous := fmt.Sprintf("Anonymous #%d", bob.ID)
+	players, _ := store.GetAllPlayers()
+	if players[1].DisplayName != anonymous || players[1].Username != "" {
+		t.Errorf("expected Bob to be anonymized, got %+v", players[1])
+	}
+	results, _ := store.GetTournamentResults(7)
+	if len(results) != 1 || results[0].Player2 != anonymous {
+		t.Errorf("expected Bob to be anonymized in results, got %+v", results)
+	}
+	history, _ := store.GetPlayerMatchHistory("Alice")
+	if len(history) != 1 || history[0].OpponentName != anonymous {
+		t.Errorf("expected Bob to be anonymized in Alice's history, got %+v", history)
+	}
+	matchups, _ := store.GetMatchups()
+	for _, m := range matchups {
+		if m.Player1 == "Bob" || m.Player2 == "Bob" {
+			t.Errorf("expected Bob to be anonymized in matchups, got %+v", m)
+		}
+	}
+
+	// Administration still sees who he is
+	found, err := store.FindPlayer("bob")
+	if err != nil || found == nil || found.ID != bob.ID || found.DisplayName != "Bob" {
+		t.Fatalf("expected to find Bob by his real name, got %+v, %v", found, err)
+	}
+	profile, _ := store.GetPlayerProfile(bob.ID)
+	if profile == nil || !profile.OptOut || profile.DisplayName != "Bob" || profile.Region != "Lyon" {
+		t.Errorf("unexpected stored profile: %+v", profile)
+	}
+
+	profile.OptOut = false
+	store.SavePlayerProfile(*profile)
+	if players, _ := store.GetAllPlayers(); players[1].DisplayName != "Bob" {
+		t.Errorf("expected Bob's name back after opting in, got %q", players[1].DisplayName)
+	}
+	if missing, err := store.FindPlayer("Carol"); err != nil || missing != nil {
+		t.Errorf("expected no player, got %+v, %v", missing, err)
+	}
+}
+
+func TestGroupRankings(t *testing.T) {
+	store := createTestDB(t)
+	defer store.Close()
+
+	// name, rating, region, team
+	players := []struct {
+		name         string
+		elo          int
+		region, team string
+	}{
+		{"Alice", 1700, "Paris", "Les Bleus"},
+		{"Bob", 1600, "Paris", "Les Bleus"},
+		{"Carol", 1650, "Lyon", "Gones"},
+		{"Dave", 1550, "Lyon", "Les Bleus"},
+		{"Erin", 1800, "Lyon", "Gones"},
+		{"Frank", 1750, "Paris", "Gones"},
+	}
+	for i, p := range players {
+		player, _ := store.GetOrCreatePlayer(int64(i+1), p.name, "")
+		store.db.Exec(`UPDATE players SET current_elo = ?, matches_played = 10 WHERE id = ?`, p.elo, player.ID)
+		store.SavePlayerProfile(PlayerProfile{PlayerID: player.ID, Region: p.region, Team: p.team, OptOut: p.name == "Frank"})
+	}
+
+	// Frank opted out, so his profile is not published
+	regions, err := store.GetGroups(GroupRegion)
+	if err != nil {
+		t.Fatalf("failed to get regions: %v", err)
+	}
+	if strings.Join(regions, ",") != "Lyon,Paris" {
+		t.Errorf("unexpected regions: %v", regions)
+	}
+
+	paris, err := store.GetGroupRankings(GroupRegion, "Paris", 10)
+	if err != nil {
+		t.Fatalf("failed to rank Paris: %v", err)
+	}
+	if len(paris) != 2 || paris[0].DisplayName != "Alice" || paris[0].Rank != 1 || paris[1].DisplayName != "Bob" || paris[1].Rank != 2 {
+		t.Errorf("expected Alice and Bob ranked among themselves, got %+v", paris)
+	}
+	if none, _ := store.GetGroupRankings(GroupRegion, "Paris", 11); len(none) != 0 {
+		t.Errorf("expected the match threshold to apply, got %+v", none)
+	}
+	if _, err := store.GetGroupRankings(PlayerGroup("name"), "Alice", 0); err == nil {
+		t.Error("expected an error for an unknown group")
+	}
+
+	clubs, err := store.GetClubRankings(2, 10)
+	if err != nil {
+		t.Fatalf("failed to rank clubs: %v", err)
+	}
+	// Gones: Erin and Carol average 1725; Les Bleus: Alice and Bob 1650
+	if len(clubs) != 2 || clubs[0].Team != "Gones" || clubs[0].AverageELO != 1725 || clubs[0].Members != 2 {
+		t.Fatalf("unexpected club table: %+v", clubs)
+	}
+	if clubs[1].Team != "Les Bleus" || clubs[1].Rank != 2 || clubs[1].AverageELO != 1650 || clubs[1].Members != 3 {
+		t.Errorf("unexpected second club: %+v", clubs[1])
+	}
+	if clubs, _ := store.GetClubRankings(3, 10); len(clubs) != 1 || clubs[0].Team != "Les Bleus" {
+		t.Errorf("expected clubs without enough ranked players to be left out, got %+v", clubs)
+	}
 }